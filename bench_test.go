@@ -0,0 +1,94 @@
+package httpspy
+
+import (
+	"net/http"
+	"sync/atomic"
+	"testing"
+)
+
+// The benchmarks below measure the cost of wrapping a ResponseWriter with a
+// spy.  Each operation creates a spy and writes one body through it in 4KB
+// chunks, so ns/op and allocs/op include construction.  Compare the
+// capturing WriteSpy with the count-only LabelSpy and the bare Spy to judge
+// the overhead of body capture.
+//
+//	go test -run NONE -bench . -benchmem
+
+// discardWriter is a ResponseWriter that does no work.
+type discardWriter struct {
+	h http.Header
+}
+
+func (w *discardWriter) Header() http.Header         { return w.h }
+func (w *discardWriter) WriteHeader(int)             {}
+func (w *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+
+var benchSizes = []struct {
+	name string
+	size int
+}{
+	{"small", 128},
+	{"medium", 16 << 10},
+	{"large", 1 << 20},
+}
+
+var benchSpies = []struct {
+	name string
+	new  func(http.ResponseWriter) Spy
+}{
+	{"Spy", NewSpy},
+	{"LabelSpy", func(w http.ResponseWriter) Spy { return NewLabelSpy(w) }},
+	{"WriteSpy", func(w http.ResponseWriter) Spy { return NewWriteSpy(w) }},
+}
+
+func writeBody(w http.ResponseWriter, body []byte) {
+	for len(body) > 0 {
+		n := 4 << 10
+		if n > len(body) {
+			n = len(body)
+		}
+		w.Write(body[:n])
+		body = body[n:]
+	}
+}
+
+func BenchmarkSpies(b *testing.B) {
+	for _, spy := range benchSpies {
+		for _, size := range benchSizes {
+			body := make([]byte, size.size)
+			b.Run(spy.name+"/"+size.name, func(b *testing.B) {
+				w := &discardWriter{h: make(http.Header)}
+				b.SetBytes(int64(len(body)))
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					writeBody(spy.new(w), body)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkSpiesConcurrent writes through shared spies from many goroutines
+// to measure lock contention.  Each operation is one 4KB write and a new spy
+// is shared every 256 writes to bound the memory captured by WriteSpy.
+func BenchmarkSpiesConcurrent(b *testing.B) {
+	body := make([]byte, 4<<10)
+	for _, spy := range benchSpies {
+		b.Run(spy.name, func(b *testing.B) {
+			w := &discardWriter{h: make(http.Header)}
+			var cur atomic.Value
+			var count int64
+			cur.Store(spy.new(w))
+			b.SetBytes(int64(len(body)))
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if atomic.AddInt64(&count, 1)%256 == 0 {
+						cur.Store(spy.new(w))
+					}
+					cur.Load().(Spy).Write(body)
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package httpspy
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// BreakerState is the state of a Breaker.
+type BreakerState int
+
+// The states of a Breaker.
+const (
+	// BreakerClosed passes all requests to the handler.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen rejects all requests with 503 until the cooldown ends.
+	BreakerOpen
+	// BreakerHalfOpen passes a single trial request to the handler.
+	BreakerHalfOpen
+)
+
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	}
+	return "BreakerState(" + strconv.Itoa(int(s)) + ")"
+}
+
+// A Breaker is a circuit breaker driven by the status codes its handler
+// responds with.  When the fraction of 5xx responses among recent requests
+// reaches a threshold the breaker opens and rejects requests with 503
+// (service unavailable) without calling the handler.  After a cooldown a
+// single trial request is let through, closing the breaker if it succeeds and
+// reopening it otherwise.  A handler panic counts as a 5xx response.  A
+// Breaker is safe for concurrent use.
+type Breaker struct {
+	h         http.Handler
+	threshold float64
+	cooldown  time.Duration
+
+	mut      sync.Mutex
+	state    BreakerState
+	gen      uint64 // incremented on every state change
+	openedAt time.Time
+	trial    bool
+	outcomes []bool // ring buffer of recent outcomes, true for 5xx
+	next     int
+	filled   int
+	failures int
+}
+
+// NewBreaker returns a Breaker for h that opens when at least threshold, a
+// fraction between 0 and 1, of the last window responses were 5xx.  The
+// breaker does not open until window responses have been seen.  An open
+// breaker admits a trial request after cooldown.  A threshold above 1 is
+// treated as 1, and one that is not positive as a single 5xx in the window,
+// so a breaker never opens on successful traffic.
+func NewBreaker(h http.Handler, threshold float64, window int, cooldown time.Duration) *Breaker {
+	if window < 1 {
+		window = 1
+	}
+	switch {
+	case !(threshold > 0):
+		threshold = 1 / float64(window)
+	case threshold > 1:
+		threshold = 1
+	}
+	b := new(Breaker)
+	b.h = h
+	b.threshold = threshold
+	b.cooldown = cooldown
+	b.outcomes = make([]bool, window)
+	return b
+}
+
+// State returns the current state of b.
+func (b *Breaker) State() BreakerState {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	if b.state == BreakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		return BreakerHalfOpen
+	}
+	return b.state
+}
+
+func (b *Breaker) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	a, retry, ok := b.admit()
+	if !ok {
+		resp.Header().Set("Retry-After", strconv.Itoa(int((retry+time.Second-1)/time.Second)))
+		http.Error(resp, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		return
+	}
+
+	spy := NewSpy(resp)
+	failed := true
+	defer func() { b.record(a, failed) }()
+	b.h.ServeHTTP(spy, req)
+	failed = spy.Code() >= 500
+}
+
+// An admission identifies the breaker state a request was admitted in, so
+// that outcomes of requests that outlive a state change are ignored.
+type admission struct {
+	gen   uint64
+	trial bool
+}
+
+// admit returns the admission of a request that may be passed to the
+// handler, or the time until the breaker admits a trial request.
+func (b *Breaker) admit() (admission, time.Duration, bool) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		wait := b.cooldown - time.Since(b.openedAt)
+		if wait > 0 {
+			return admission{}, wait, false
+		}
+		b.transition(BreakerHalfOpen)
+		b.trial = true
+		return admission{gen: b.gen, trial: true}, 0, true
+	case BreakerHalfOpen:
+		if b.trial {
+			return admission{}, 0, false
+		}
+		b.trial = true
+		return admission{gen: b.gen, trial: true}, 0, true
+	}
+	return admission{gen: b.gen}, 0, true
+}
+
+func (b *Breaker) record(a admission, failed bool) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	if a.gen != b.gen {
+		// The request was admitted before the last state change.
+		return
+	}
+	if a.trial {
+		b.trial = false
+		if failed {
+			b.open()
+		} else {
+			b.transition(BreakerClosed)
+		}
+		return
+	}
+
+	if b.filled == len(b.outcomes) {
+		if b.outcomes[b.next] {
+			b.failures--
+		}
+	} else {
+		b.filled++
+	}
+	b.outcomes[b.next] = failed
+	if failed {
+		b.failures++
+	}
+	b.next = (b.next + 1) % len(b.outcomes)
+
+	if b.filled == len(b.outcomes) && float64(b.failures)/float64(b.filled) >= b.threshold {
+		b.open()
+	}
+}
+
+// transition changes the state of b, clearing the window and starting a new
+// generation of admissions.  It must be called with b.mut held.
+func (b *Breaker) transition(state BreakerState) {
+	b.state = state
+	b.gen++
+	b.reset()
+}
+
+// open must be called with b.mut held.
+func (b *Breaker) open() {
+	b.transition(BreakerOpen)
+	b.openedAt = time.Now()
+}
+
+// reset must be called with b.mut held.
+func (b *Breaker) reset() {
+	for i := range b.outcomes {
+		b.outcomes[i] = false
+	}
+	b.next, b.filled, b.failures = 0, 0, 0
+}
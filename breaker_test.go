@@ -0,0 +1,126 @@
+package httpspy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBreaker(t *testing.T) {
+	fail := true
+	calls := 0
+	b := NewBreaker(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		calls++
+		if fail {
+			http.Error(resp, "oops", http.StatusInternalServerError)
+		}
+	}), 0.5, 4, 20*time.Millisecond)
+
+	serve := func() int {
+		rec := httptest.NewRecorder()
+		b.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		return rec.Code
+	}
+
+	for i := 0; i < 4; i++ {
+		if code := serve(); code != 500 {
+			t.Fatalf("request %d: %d", i, code)
+		}
+	}
+	if b.State() != BreakerOpen {
+		t.Fatalf("state %v", b.State())
+	}
+	if code := serve(); code != 503 || calls != 4 {
+		t.Fatalf("open breaker: code %d, %d calls", code, calls)
+	}
+
+	// A failed trial reopens the breaker.
+	time.Sleep(25 * time.Millisecond)
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("state %v", b.State())
+	}
+	if code := serve(); code != 500 || b.State() != BreakerOpen {
+		t.Fatalf("failed trial: code %d, state %v", code, b.State())
+	}
+
+	// A successful trial closes it.
+	time.Sleep(25 * time.Millisecond)
+	fail = false
+	if code := serve(); code != 200 || b.State() != BreakerClosed {
+		t.Fatalf("successful trial: code %d, state %v", code, b.State())
+	}
+}
+
+func TestBreakerStaleOutcome(t *testing.T) {
+	entered := make(chan string)
+	release := map[string]chan struct{}{
+		"/slow":  make(chan struct{}),
+		"/trial": make(chan struct{}),
+	}
+	b := NewBreaker(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if c := release[req.URL.Path]; c != nil {
+			entered <- req.URL.Path
+			<-c
+		}
+		if req.URL.Path != "/slow" {
+			http.Error(resp, "oops", http.StatusInternalServerError)
+		}
+	}), 0.5, 4, 20*time.Millisecond)
+
+	serve := func(path string, done chan<- int) {
+		rec := httptest.NewRecorder()
+		b.ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		done <- rec.Code
+	}
+
+	// A slow request admitted while closed stays in flight while the
+	// breaker opens.
+	slow := make(chan int, 1)
+	go serve("/slow", slow)
+	<-entered
+	codes := make(chan int, 4)
+	for i := 0; i < 4; i++ {
+		serve("/", codes)
+	}
+	if b.State() != BreakerOpen {
+		t.Fatalf("state %v", b.State())
+	}
+
+	time.Sleep(25 * time.Millisecond)
+	trial := make(chan int, 1)
+	go serve("/trial", trial)
+	<-entered
+
+	// The slow success finishing during the trial must not close the
+	// breaker.
+	close(release["/slow"])
+	if code := <-slow; code != 200 {
+		t.Fatalf("slow request: %d", code)
+	}
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("stale outcome changed state to %v", b.State())
+	}
+
+	close(release["/trial"])
+	if code := <-trial; code != 500 {
+		t.Fatalf("trial request: %d", code)
+	}
+	if b.State() != BreakerOpen {
+		t.Fatalf("failed trial left state %v", b.State())
+	}
+}
+
+func TestBreakerThreshold(t *testing.T) {
+	ok := http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {})
+	for _, threshold := range []float64{0, -1, 2} {
+		b := NewBreaker(ok, threshold, 2, time.Minute)
+		for i := 0; i < 4; i++ {
+			rec := httptest.NewRecorder()
+			b.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+			if rec.Code != 200 {
+				t.Fatalf("threshold %v: response %d: code %d", threshold, i, rec.Code)
+			}
+		}
+	}
+}
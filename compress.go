@@ -0,0 +1,424 @@
+package httpspy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// compressMinSize is the smallest body a CompressSpy will compress.  Smaller
+// bodies gain little and may grow from gzip framing.
+const compressMinSize = 1024
+
+// A CompressSpy is a Spy that transparently gzips response bodies on their
+// way to the underlying http.ResponseWriter.  Code and BytesWritten report the
+// logical, uncompressed response.
+type CompressSpy interface {
+	Spy
+	// BytesWritten returns the number of uncompressed body bytes written by
+	// the handler.
+	BytesWritten() int64
+	// UncompressedBytes is equivalent to BytesWritten.
+	UncompressedBytes() int64
+	// CompressedBytes returns the number of body bytes written to the
+	// underlying writer.  It equals BytesWritten when the response was not
+	// compressed.
+	CompressedBytes() int64
+	// Compressed returns true if the response body is gzipped.
+	Compressed() bool
+	// CompressionSkipped returns true and the reason if the spy decided not
+	// to compress the response.
+	CompressionSkipped() (skipped bool, reason string)
+	// Close flushes any buffered or compressed bytes to the underlying writer.
+	// Close must be called after the handler returns.
+	Close() error
+}
+
+// NewCompressSpy returns a generic, threadsafe CompressSpy implementation.
+// The response is compressed when req accepts gzip encoding, the response
+// Content-Type is textual (text/*, JSON, XML, JavaScript or SVG), no
+// Content-Encoding was set by the handler, and the body is at least 1KB.
+// Responses with a compressible Content-Type get a "Vary: Accept-Encoding"
+// header whether or not they are compressed.
+//
+// When gzip is possible the first kilobyte or more of the body is buffered and
+// compressed on trial before the response header is written.  If gzip does
+// not make those bytes smaller the response is sent uncompressed, without a
+// Content-Encoding.  When the request or the response header already rules
+// out compression nothing is buffered.
+func NewCompressSpy(w http.ResponseWriter, req *http.Request) CompressSpy {
+	s := new(compressSpy)
+	s.w = w
+	s.gzipOK = req.Method != "HEAD" && acceptsGzip(req.Header.Get("Accept-Encoding"))
+	return s
+}
+
+type compressSpy struct {
+	w       http.ResponseWriter
+	gzipOK  bool
+	mut     sync.Mutex
+	code    int
+	written bool
+	decided bool
+	closed  bool
+	buf     []byte
+	gz      *gzip.Writer
+	trial   *bytes.Buffer
+	n       int64
+	wire    int64
+
+	skipReason string
+}
+
+func (s *compressSpy) Header() http.Header {
+	return s.w.Header()
+}
+
+func (s *compressSpy) WriteHeader(code int) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.code != 0 || s.written {
+		return
+	}
+	s.code = code
+	if !bodyAllowed(code) {
+		s.decide(false)
+	}
+}
+
+func (s *compressSpy) Write(p []byte) (int, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.written = true
+	if s.code == 0 {
+		s.code = http.StatusOK
+	}
+	if !s.decided {
+		s.buf = append(s.buf, p...)
+		s.n += int64(len(p))
+		if s.ruledOut() {
+			return len(p), s.decide(false)
+		}
+		if len(s.buf) < compressMinSize {
+			return len(p), nil
+		}
+		return len(p), s.decide(true)
+	}
+
+	var n int
+	var err error
+	if s.gz != nil {
+		n, err = s.gz.Write(p)
+	} else {
+		n, err = s.w.Write(p)
+		s.wire += int64(n)
+	}
+	s.n += int64(n)
+	return n, err
+}
+
+// ruledOut returns true if the response cannot be compressed regardless of
+// its body.  It must be called with s.mut held.
+func (s *compressSpy) ruledOut() bool {
+	h := s.w.Header()
+	ct := h.Get("Content-Type")
+	return !s.gzipOK || h.Get("Content-Encoding") != "" || (ct != "" && !compressibleType(ct))
+}
+
+// decide commits the response header and writes any buffered bytes.  The
+// body is only compressed if large is true.  It must be called with s.mut
+// held.
+func (s *compressSpy) decide(large bool) error {
+	s.decided = true
+	h := s.w.Header()
+	if h.Get("Content-Type") == "" && len(s.buf) > 0 {
+		h.Set("Content-Type", http.DetectContentType(s.buf))
+	}
+	switch {
+	case !bodyAllowed(s.code):
+		s.skipReason = "response has no body"
+	case h.Get("Content-Encoding") != "":
+		s.skipReason = "response already encoded"
+	case !compressibleType(h.Get("Content-Type")):
+		s.skipReason = "content type not compressible"
+	}
+	if s.skipReason == "" && !headerHasToken(h, "Vary", "Accept-Encoding") {
+		h.Add("Vary", "Accept-Encoding")
+	}
+	switch {
+	case s.skipReason != "":
+	case !s.gzipOK:
+		s.skipReason = "request does not accept gzip"
+	case !large:
+		s.skipReason = "body too small"
+	}
+
+	buf := s.buf
+	s.buf = nil
+	var compressed []byte
+	if s.skipReason == "" {
+		// Compress the buffered bytes before committing so the response can
+		// be sent uncompressed if gzip would make it larger.
+		var trial bytes.Buffer
+		s.trial = &trial
+		s.gz = gzip.NewWriter(compressWire{s})
+		s.gz.Write(buf)
+		s.gz.Flush()
+		s.trial = nil
+		if trial.Len() < len(buf) {
+			compressed = trial.Bytes()
+			h.Set("Content-Encoding", "gzip")
+			h.Del("Content-Length")
+		} else {
+			s.gz = nil
+			s.skipReason = "compression did not reduce size"
+		}
+	}
+	if s.code != 0 {
+		s.w.WriteHeader(s.code)
+	}
+
+	if s.gz != nil {
+		_, err := compressWire{s}.Write(compressed)
+		return err
+	}
+	if len(buf) == 0 {
+		return nil
+	}
+	n, err := s.w.Write(buf)
+	s.wire += int64(n)
+	return err
+}
+
+func (s *compressSpy) Close() error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if !s.decided && s.code != 0 {
+		if err := s.decide(false); err != nil {
+			return err
+		}
+	}
+	if s.gz != nil {
+		return s.gz.Close()
+	}
+	return nil
+}
+
+func (s *compressSpy) Code() int {
+	s.mut.Lock()
+	code := s.code
+	s.mut.Unlock()
+	return code
+}
+
+func (s *compressSpy) BytesWritten() int64 {
+	s.mut.Lock()
+	n := s.n
+	s.mut.Unlock()
+	return n
+}
+
+func (s *compressSpy) CompressionSkipped() (bool, string) {
+	s.mut.Lock()
+	reason := s.skipReason
+	s.mut.Unlock()
+	return reason != "", reason
+}
+
+func (s *compressSpy) UncompressedBytes() int64 {
+	return s.BytesWritten()
+}
+
+func (s *compressSpy) CompressedBytes() int64 {
+	s.mut.Lock()
+	n := s.wire
+	s.mut.Unlock()
+	return n
+}
+
+func (s *compressSpy) Compressed() bool {
+	s.mut.Lock()
+	ok := s.gz != nil
+	s.mut.Unlock()
+	return ok
+}
+
+// CompressStats sums the bandwidth saved by compression across responses.  A
+// CompressStats is safe for concurrent use.
+type CompressStats struct {
+	mut          sync.Mutex
+	uncompressed int64
+	compressed   int64
+}
+
+// Add records the response observed by s.  Add should only be called after
+// s has been closed.  Responses that were not compressed count toward the
+// totals but save nothing.
+func (c *CompressStats) Add(s CompressSpy) {
+	u, z := s.UncompressedBytes(), s.CompressedBytes()
+	c.mut.Lock()
+	c.uncompressed += u
+	c.compressed += z
+	c.mut.Unlock()
+}
+
+// Savings returns the total number of bytes saved by compression and the
+// savings as a percentage of uncompressed bytes.
+func (c *CompressStats) Savings() (total int64, percent float64) {
+	c.mut.Lock()
+	u, z := c.uncompressed, c.compressed
+	c.mut.Unlock()
+	if u == 0 {
+		return 0, 0
+	}
+	total = u - z
+	return total, 100 * float64(total) / float64(u)
+}
+
+// A CompressionPolicy decides whether a response should have been compressed,
+// to audit the thresholds of a compression middleware.  The zero value uses
+// the thresholds of NewCompressSpy.
+type CompressionPolicy struct {
+	// MinSize is the smallest body eligible for compression.  Zero means
+	// 1024 bytes.
+	MinSize int64
+	// Types lists the compressible media types.  An entry such as "text/*"
+	// matches every subtype.  If nil the textual types compressed by
+	// NewCompressSpy are used.
+	Types []string
+}
+
+// CompressionEligible is equivalent to CompressionPolicy{}.Eligible(s).
+func CompressionEligible(s Spy) bool {
+	return CompressionPolicy{}.Eligible(s)
+}
+
+// Eligible returns true if the response observed by s has a compressible
+// Content-Type and at least p.MinSize body bytes.  The byte count is taken
+// from a BytesWritten method or, for a WriteSpy, the captured body.  Spies
+// reporting neither are never eligible.  The Content-Type is read from
+// s.Header(), so Eligible should only be called after the handler returns.
+func (p CompressionPolicy) Eligible(s Spy) bool {
+	var n int64
+	switch s := s.(type) {
+	case interface {
+		BytesWritten() int64
+	}:
+		n = s.BytesWritten()
+	case WriteSpy:
+		n = int64(len(s.Body()))
+	default:
+		return false
+	}
+	min := p.MinSize
+	if min == 0 {
+		min = compressMinSize
+	}
+	if n < min {
+		return false
+	}
+
+	typ := mediaType(s.Header().Get("Content-Type"))
+	if p.Types == nil {
+		return compressibleType(typ)
+	}
+	for _, t := range p.Types {
+		t = strings.ToLower(t)
+		if t == typ || (strings.HasSuffix(t, "/*") && strings.HasPrefix(typ, t[:len(t)-1])) {
+			return true
+		}
+	}
+	return false
+}
+
+// compressWire counts the compressed bytes a compressSpy writes to its
+// underlying writer, or diverts them to the spy's trial buffer.  It is only
+// used with the spy's mutex held.
+type compressWire struct {
+	s *compressSpy
+}
+
+func (w compressWire) Write(p []byte) (int, error) {
+	if w.s.trial != nil {
+		return w.s.trial.Write(p)
+	}
+	n, err := w.s.w.Write(p)
+	w.s.wire += int64(n)
+	return n, err
+}
+
+func bodyAllowed(code int) bool {
+	return code >= 200 && code != http.StatusNoContent && code != http.StatusNotModified
+}
+
+// acceptsGzip returns true if the Accept-Encoding header value ae allows a
+// gzip response body.  An explicit gzip entry takes precedence over *.
+func acceptsGzip(ae string) bool {
+	wildcard := false
+	for _, part := range strings.Split(ae, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, _ = strconv.ParseFloat(param[2:], 64)
+			}
+		}
+		if coding == "gzip" {
+			return q > 0
+		}
+		wildcard = q > 0
+	}
+	return wildcard
+}
+
+// compressibleType returns true if the media type of ct is textual.
+func compressibleType(ct string) bool {
+	typ := mediaType(ct)
+	switch {
+	case typ == "text/event-stream":
+		return false
+	case strings.HasPrefix(typ, "text/"):
+		return true
+	case strings.HasSuffix(typ, "+json"), strings.HasSuffix(typ, "+xml"):
+		return true
+	}
+	switch typ {
+	case "application/json", "application/javascript", "application/xml",
+		"image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// mediaType returns the lowercased media type of ct without parameters.
+func mediaType(ct string) string {
+	if i := strings.Index(ct, ";"); i >= 0 {
+		ct = ct[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(ct))
+}
+
+// headerHasToken returns true if a comma separated list in header key of h
+// contains token, compared case insensitively.
+func headerHasToken(h http.Header, key, token string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(key)] {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package httpspy
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCompressSpy(t *testing.T) {
+	body := strings.Repeat("hello, world\n", 200)
+	for _, test := range []struct {
+		accept     string
+		ctype      string
+		body       string
+		compressed bool
+	}{
+		{"gzip, deflate", "text/plain; charset=utf-8", body, true},
+		{"", "text/plain", body, false},
+		{"gzip;q=0", "text/plain", body, false},
+		{"*;q=1, gzip;q=0", "text/plain", body, false},
+		{"gzip;q=1, *;q=0", "text/plain", body, true},
+		{"*", "text/plain", body, true},
+		{"gzip", "image/png", body, false},
+		{"gzip", "application/json", "{}", false},
+		{"gzip", "", body, true},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", test.accept)
+		rec := httptest.NewRecorder()
+		s := NewCompressSpy(rec, req)
+		if test.ctype != "" {
+			s.Header().Set("Content-Type", test.ctype)
+		}
+		io.WriteString(s, test.body[:len(test.body)/2])
+		io.WriteString(s, test.body[len(test.body)/2:])
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		if s.Compressed() != test.compressed {
+			t.Errorf("%q %q: compressed=%v", test.accept, test.ctype, s.Compressed())
+		}
+		if s.Code() != http.StatusOK {
+			t.Errorf("%q %q: code=%d", test.accept, test.ctype, s.Code())
+		}
+		if s.BytesWritten() != int64(len(test.body)) {
+			t.Errorf("%q %q: bytes written %d", test.accept, test.ctype, s.BytesWritten())
+		}
+		if s.CompressedBytes() != int64(rec.Body.Len()) {
+			t.Errorf("%q %q: compressed bytes %d (wire %d)", test.accept, test.ctype, s.CompressedBytes(), rec.Body.Len())
+		}
+
+		got := rec.Body.Bytes()
+		if test.compressed {
+			if rec.Header().Get("Content-Encoding") != "gzip" {
+				t.Errorf("%q %q: missing Content-Encoding", test.accept, test.ctype)
+			}
+			r, err := gzip.NewReader(rec.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err = io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if !bytes.Equal(got, []byte(test.body)) {
+			t.Errorf("%q %q: body mismatch", test.accept, test.ctype)
+		}
+		if compressibleType(rec.Header().Get("Content-Type")) && rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%q %q: vary %q", test.accept, test.ctype, rec.Header().Get("Vary"))
+		}
+	}
+}
+
+func TestCompressSpyNoBody(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	s := NewCompressSpy(rec, req)
+	s.WriteHeader(http.StatusNotModified)
+	s.Close()
+	if rec.Code != http.StatusNotModified || s.Compressed() || rec.Body.Len() != 0 {
+		t.Errorf("code=%d compressed=%v body=%q", rec.Code, s.Compressed(), rec.Body)
+	}
+}
+
+func TestCompressStats(t *testing.T) {
+	var stats CompressStats
+	body := strings.Repeat("a", 4096)
+	for _, accept := range []string{"gzip", ""} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", accept)
+		rec := httptest.NewRecorder()
+		s := NewCompressSpy(rec, req)
+		s.Header().Set("Content-Type", "text/plain")
+		io.WriteString(s, body)
+		s.Close()
+		stats.Add(s)
+	}
+
+	total, percent := stats.Savings()
+	if total <= 0 || total >= 4096 {
+		t.Errorf("total savings %d", total)
+	}
+	if want := 100 * float64(total) / 8192; percent != want {
+		t.Errorf("percent %v != %v", percent, want)
+	}
+}
+
+func TestCompressSpySkipped(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(random)
+	for _, test := range []struct {
+		accept string
+		ctype  string
+		body   []byte
+		reason string
+	}{
+		{"gzip", "text/plain", bytes.Repeat([]byte("a"), 4096), ""},
+		{"gzip", "text/plain", random, "compression did not reduce size"},
+		{"gzip", "text/plain", []byte("tiny"), "body too small"},
+		{"", "text/plain", random, "request does not accept gzip"},
+		{"gzip", "image/png", random, "content type not compressible"},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", test.accept)
+		rec := httptest.NewRecorder()
+		s := NewCompressSpy(rec, req)
+		s.Header().Set("Content-Type", test.ctype)
+		s.Write(test.body)
+		s.Close()
+
+		skipped, reason := s.CompressionSkipped()
+		if skipped != (test.reason != "") || reason != test.reason {
+			t.Errorf("%q %q: skipped=%v reason=%q", test.accept, test.ctype, skipped, reason)
+		}
+		if skipped && (rec.Header().Get("Content-Encoding") != "" || !bytes.Equal(rec.Body.Bytes(), test.body)) {
+			t.Errorf("%q %q: skipped compression but response encoded", test.accept, test.ctype)
+		}
+	}
+}
+
+func TestCompressSpyPassthrough(t *testing.T) {
+	for _, test := range []struct {
+		accept string
+		header string
+		value  string
+		reason string
+	}{
+		{"", "Content-Type", "text/plain", "request does not accept gzip"},
+		{"gzip", "Content-Type", "image/png", "content type not compressible"},
+		{"gzip", "Content-Encoding", "br", "response already encoded"},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("Accept-Encoding", test.accept)
+		rec := httptest.NewRecorder()
+		s := NewCompressSpy(rec, req)
+		s.Header().Set(test.header, test.value)
+		io.WriteString(s, "first")
+		if rec.Body.String() != "first" {
+			t.Errorf("%q %s: buffered %q", test.accept, test.value, rec.Body)
+		}
+		if _, reason := s.CompressionSkipped(); reason != test.reason {
+			t.Errorf("%q %s: reason %q", test.accept, test.value, reason)
+		}
+		s.Close()
+	}
+}
+
+func TestCompressionEligible(t *testing.T) {
+	big := strings.Repeat("a", 2048)
+	for _, test := range []struct {
+		policy   CompressionPolicy
+		ctype    string
+		body     string
+		eligible bool
+	}{
+		{CompressionPolicy{}, "text/plain", big, true},
+		{CompressionPolicy{}, "application/json", big[:1024], true},
+		{CompressionPolicy{}, "text/plain", big[:1023], false},
+		{CompressionPolicy{}, "image/png", big, false},
+		{CompressionPolicy{MinSize: 10}, "text/html", "0123456789", true},
+		{CompressionPolicy{Types: []string{"application/wasm"}}, "application/wasm", big, true},
+		{CompressionPolicy{Types: []string{"application/wasm"}}, "text/plain", big, false},
+		{CompressionPolicy{Types: []string{"Text/*"}}, "text/csv; charset=utf-8", big, true},
+	} {
+		s := NewWriteSpy(httptest.NewRecorder())
+		s.Header().Set("Content-Type", test.ctype)
+		io.WriteString(s, test.body)
+		if test.policy.Eligible(s) != test.eligible {
+			t.Errorf("%+v %q %d: eligible=%v", test.policy, test.ctype, len(test.body), !test.eligible)
+		}
+	}
+
+	ls := NewLabelSpy(httptest.NewRecorder())
+	ls.Header().Set("Content-Type", "text/plain")
+	io.WriteString(ls, big)
+	if !CompressionEligible(ls) {
+		t.Errorf("label spy not eligible")
+	}
+	if CompressionEligible(NewSpy(httptest.NewRecorder())) {
+		t.Errorf("spy without a byte count eligible")
+	}
+}
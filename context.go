@@ -0,0 +1,103 @@
+package httpspy
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// A ContextWriteSpy is a WriteSpy whose capture is bounded by the lifetime of
+// a context, typically the request context.
+type ContextWriteSpy interface {
+	WriteSpy
+	// CaptureStopped returns true once the context is done.
+	CaptureStopped() bool
+	// DiscardedBytes returns the number of bytes passed to Write after the
+	// context was done, none of which reached the client.
+	DiscardedBytes() int64
+}
+
+// NewContextWriteSpy returns a generic, threadsafe ContextWriteSpy.  When ctx
+// is done the spy stops capturing, drops its reference to w, and fails all
+// further calls to Write with ctx.Err().  Bytes captured before ctx was done
+// remain available from Body.
+//
+// Because the request context is done once ServeHTTP returns, a goroutine
+// leaked by the handler cannot write to w or grow the captured body through
+// the spy.  Header returns a detached http.Header after ctx is done.
+func NewContextWriteSpy(ctx context.Context, w http.ResponseWriter) ContextWriteSpy {
+	s := new(contextWriteSpy)
+	s.simpleWriteSpy = NewWriteSpy(w).(*simpleWriteSpy)
+	s.ctx = ctx
+	context.AfterFunc(ctx, func() {
+		s.mut.Lock()
+		s.stopCapture()
+		s.mut.Unlock()
+	})
+	return s
+}
+
+type contextWriteSpy struct {
+	*simpleWriteSpy
+	ctx       context.Context
+	mut       sync.Mutex
+	stopped   bool
+	discarded int64
+}
+
+// stopCapture must be called with s.mut held.
+func (s *contextWriteSpy) stopCapture() {
+	if !s.stopped {
+		s.stopped = true
+		s.simpleSpy.w = nil
+	}
+}
+
+// checkStopped must be called with s.mut held.
+func (s *contextWriteSpy) checkStopped() bool {
+	if !s.stopped && s.ctx.Err() != nil {
+		s.stopCapture()
+	}
+	return s.stopped
+}
+
+func (s *contextWriteSpy) Header() http.Header {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.checkStopped() {
+		return make(http.Header)
+	}
+	return s.simpleWriteSpy.Header()
+}
+
+func (s *contextWriteSpy) WriteHeader(code int) {
+	s.mut.Lock()
+	if !s.checkStopped() {
+		s.simpleWriteSpy.WriteHeader(code)
+	}
+	s.mut.Unlock()
+}
+
+func (s *contextWriteSpy) Write(p []byte) (int, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.checkStopped() {
+		s.discarded += int64(len(p))
+		return 0, s.ctx.Err()
+	}
+	return s.simpleWriteSpy.Write(p)
+}
+
+func (s *contextWriteSpy) CaptureStopped() bool {
+	s.mut.Lock()
+	stopped := s.checkStopped()
+	s.mut.Unlock()
+	return stopped
+}
+
+func (s *contextWriteSpy) DiscardedBytes() int64 {
+	s.mut.Lock()
+	n := s.discarded
+	s.mut.Unlock()
+	return n
+}
@@ -0,0 +1,33 @@
+package httpspy
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestContextWriteSpy(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	rec := httptest.NewRecorder()
+	s := NewContextWriteSpy(ctx, rec)
+	io.WriteString(s, "before")
+	if s.CaptureStopped() {
+		t.Fatalf("capture stopped before cancel")
+	}
+
+	cancel()
+	if _, err := io.WriteString(s, "after"); err != context.Canceled {
+		t.Errorf("write after cancel: %v", err)
+	}
+	if !s.CaptureStopped() {
+		t.Errorf("capture not stopped after cancel")
+	}
+	io.WriteString(s, "more")
+	if n := s.DiscardedBytes(); n != 9 {
+		t.Errorf("discarded %d bytes", n)
+	}
+	if string(s.Body()) != "before" || rec.Body.String() != "before" {
+		t.Errorf("body %q, response %q", s.Body(), rec.Body)
+	}
+}
@@ -0,0 +1,58 @@
+package httpspy
+
+import (
+	"net/http"
+	"sync"
+)
+
+// TypeStats counts the responses of one content type.
+type TypeStats struct {
+	Count int64
+	Bytes int64
+}
+
+// ContentTypeStats accumulates response counts and body bytes by media type.
+// The zero value is ready to use and a ContentTypeStats is safe for concurrent
+// use.
+type ContentTypeStats struct {
+	mut sync.Mutex
+	m   map[string]*TypeStats
+}
+
+// Add records the response observed by s under its lowercased media type,
+// without parameters.  When the handler set no Content-Type the type net/http
+// would sniff from the body is used.  Add should only be called after the
+// handler has returned.
+func (c *ContentTypeStats) Add(s WriteSpy) {
+	body := s.Body()
+	ct := s.Header().Get("Content-Type")
+	if ct == "" && len(body) > 0 {
+		ct = http.DetectContentType(body)
+	}
+	typ := mediaType(ct)
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.m == nil {
+		c.m = make(map[string]*TypeStats)
+	}
+	st := c.m[typ]
+	if st == nil {
+		st = new(TypeStats)
+		c.m[typ] = st
+	}
+	st.Count++
+	st.Bytes += int64(len(body))
+}
+
+// Snapshot returns a copy of the statistics indexed by media type.  Responses
+// without a body or Content-Type are indexed by the empty string.
+func (c *ContentTypeStats) Snapshot() map[string]TypeStats {
+	c.mut.Lock()
+	m := make(map[string]TypeStats, len(c.m))
+	for typ, st := range c.m {
+		m[typ] = *st
+	}
+	c.mut.Unlock()
+	return m
+}
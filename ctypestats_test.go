@@ -0,0 +1,36 @@
+package httpspy
+
+import (
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestContentTypeStats(t *testing.T) {
+	var stats ContentTypeStats
+	for _, r := range []struct{ ctype, body string }{
+		{"application/json", `{}`},
+		{"Application/JSON; charset=utf-8", `{"a":1}`},
+		{"", "<html></html>"},
+		{"", ""},
+	} {
+		s := NewWriteSpy(httptest.NewRecorder())
+		if r.ctype != "" {
+			s.Header().Set("Content-Type", r.ctype)
+		}
+		if r.body != "" {
+			io.WriteString(s, r.body)
+		}
+		stats.Add(s)
+	}
+
+	want := map[string]TypeStats{
+		"application/json": {2, 9},
+		"text/html":        {1, 13},
+		"":                 {1, 0},
+	}
+	if got := stats.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshot: %v", got)
+	}
+}
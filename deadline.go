@@ -0,0 +1,192 @@
+package httpspy
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A DeadlineSpy is the Spy a DeadlineHandler passes to its handler.
+type DeadlineSpy interface {
+	Spy
+	// Written returns true once the handler has called WriteHeader or Write.
+	Written() bool
+	// DeadlineExceeded returns true if the budget expired before the handler
+	// wrote a response.  The client was sent a 504 (gateway timeout) and all
+	// later writes by the handler fail with http.ErrHandlerTimeout.
+	DeadlineExceeded() bool
+}
+
+// A DeadlineHandler bounds the time a handler may take to start its response.
+// Unlike http.TimeoutHandler nothing is buffered and once the handler writes
+// a response it may stream for as long as it needs.  The spy implements
+// http.Flusher when the underlying writer does.
+type DeadlineHandler struct {
+	Handler http.Handler
+	// Budget is the time the handler has to call WriteHeader or Write.
+	Budget time.Duration
+	// OnExceeded, if not nil, is called with the request and the handler's
+	// spy after a 504 is sent because the budget expired.  It lets outer
+	// middleware record timeouts without seeing the handler's spy.
+	OnExceeded func(req *http.Request, s DeadlineSpy)
+}
+
+// ServeHTTP serves req with h.Handler in a separate goroutine, passing it a
+// DeadlineSpy.  If the budget expires before the handler writes a response,
+// ServeHTTP responds 504, cancels the handler's request context, calls
+// h.OnExceeded, and returns without waiting for the handler.  The context is not cancelled at the
+// budget for a handler that has already written.  A panic in the handler is
+// propagated unless the deadline already expired.
+func (h *DeadlineHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+	s := &deadlineSpy{w: resp, header: make(http.Header), cancel: cancel}
+
+	done := make(chan struct{})
+	panicked := make(chan interface{}, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				panicked <- p
+			}
+			close(done)
+		}()
+		h.Handler.ServeHTTP(s, req.WithContext(ctx))
+	}()
+
+	timer := time.NewTimer(h.Budget)
+	defer timer.Stop()
+	select {
+	case <-done:
+	case <-timer.C:
+		if s.expire() {
+			if h.OnExceeded != nil {
+				h.OnExceeded(req, s)
+			}
+			return
+		}
+		<-done
+	}
+	select {
+	case p := <-panicked:
+		panic(p)
+	default:
+	}
+	s.commit()
+}
+
+// deadlineSpy gives the handler its own header map, as http.TimeoutHandler
+// does, so a handler holding the map after the deadline cannot race with the
+// 504 response.  The map is copied to w when the handler first writes.
+type deadlineSpy struct {
+	w        http.ResponseWriter
+	header   http.Header
+	cancel   context.CancelFunc
+	mut      sync.Mutex
+	code     int
+	written  bool
+	exceeded bool
+}
+
+// expire responds 504 and cancels the handler's context unless the handler
+// already wrote a response.  It returns true if the response was sent.
+func (s *deadlineSpy) expire() bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.written {
+		return false
+	}
+	s.exceeded = true
+	s.cancel()
+	http.Error(s.w, http.StatusText(http.StatusGatewayTimeout), http.StatusGatewayTimeout)
+	return true
+}
+
+// commit copies the handler's header to w, marking the response written.  It
+// is used when the handler returns without writing.
+func (s *deadlineSpy) commit() {
+	s.mut.Lock()
+	s.commitHeader()
+	s.mut.Unlock()
+}
+
+// commitHeader must be called with s.mut held.
+func (s *deadlineSpy) commitHeader() {
+	if s.written || s.exceeded {
+		return
+	}
+	s.written = true
+	h := s.w.Header()
+	for k, v := range s.header {
+		h[k] = append([]string(nil), v...)
+	}
+}
+
+func (s *deadlineSpy) Header() http.Header {
+	return s.header
+}
+
+func (s *deadlineSpy) WriteHeader(code int) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.exceeded || s.written {
+		return
+	}
+	s.commitHeader()
+	s.code = code
+	s.w.WriteHeader(code)
+}
+
+func (s *deadlineSpy) Write(p []byte) (int, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.exceeded {
+		return 0, http.ErrHandlerTimeout
+	}
+	if !s.written {
+		s.commitHeader()
+		s.code = http.StatusOK
+	}
+	return s.w.Write(p)
+}
+
+// Flush commits the header and flushes the underlying writer if it is an
+// http.Flusher.  It does nothing once the deadline was exceeded.
+func (s *deadlineSpy) Flush() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.exceeded {
+		return
+	}
+	if !s.written {
+		s.commitHeader()
+		s.code = http.StatusOK
+	}
+	if f, ok := s.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (s *deadlineSpy) Code() int {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.exceeded {
+		return http.StatusGatewayTimeout
+	}
+	return s.code
+}
+
+func (s *deadlineSpy) Written() bool {
+	s.mut.Lock()
+	written := s.written
+	s.mut.Unlock()
+	return written
+}
+
+func (s *deadlineSpy) DeadlineExceeded() bool {
+	s.mut.Lock()
+	exceeded := s.exceeded
+	s.mut.Unlock()
+	return exceeded
+}
@@ -0,0 +1,83 @@
+package httpspy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeadlineHandler(t *testing.T) {
+	spies := make(chan DeadlineSpy, 1)
+	var exceeded []string
+	h := &DeadlineHandler{
+		Budget: 20 * time.Millisecond,
+		OnExceeded: func(req *http.Request, s DeadlineSpy) {
+			if !s.DeadlineExceeded() {
+				t.Errorf("%s: OnExceeded before deadline", req.URL.Path)
+			}
+			exceeded = append(exceeded, req.URL.Path)
+		},
+		Handler: http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			s := resp.(DeadlineSpy)
+			defer func() { spies <- s }()
+			h := resp.Header()
+			h.Set("X-Path", req.URL.Path)
+			if req.URL.Path == "/slow" {
+				<-req.Context().Done()
+				// The handler's header map must be safe to use late.
+				h.Set("X-Late", "1")
+				time.Sleep(5 * time.Millisecond)
+			}
+			io.WriteString(resp, "hello")
+			if req.URL.Path == "/stream" {
+				resp.(http.Flusher).Flush()
+				// Streaming past the budget is allowed once written.
+				select {
+				case <-req.Context().Done():
+					return
+				case <-time.After(40 * time.Millisecond):
+				}
+				io.WriteString(resp, ", world")
+			}
+		}),
+	}
+
+	for _, test := range []struct {
+		path     string
+		code     int
+		body     string
+		exceeded bool
+		flushed  bool
+	}{
+		{"/", 200, "hello", false, false},
+		{"/stream", 200, "hello, world", false, true},
+		{"/slow", 504, "Gateway Timeout\n", true, false},
+	} {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest("GET", test.path, nil))
+		if rec.Code != test.code || rec.Body.String() != test.body {
+			t.Errorf("%s: code=%d body=%q", test.path, rec.Code, rec.Body)
+		}
+		if rec.Flushed != test.flushed {
+			t.Errorf("%s: flushed=%v", test.path, rec.Flushed)
+		}
+		if late := rec.Header().Get("X-Late"); late != "" {
+			t.Errorf("%s: late header reached client", test.path)
+		}
+		if p := rec.Header().Get("X-Path"); p != test.path && !test.exceeded {
+			t.Errorf("%s: header X-Path=%q", test.path, p)
+		}
+		s := <-spies
+		if s.DeadlineExceeded() != test.exceeded || s.Code() != test.code || s.Written() == test.exceeded {
+			t.Errorf("%s: exceeded=%v code=%d written=%v", test.path, s.DeadlineExceeded(), s.Code(), s.Written())
+		}
+		if rec.Body.String() != test.body {
+			t.Errorf("%s: late write reached client: %q", test.path, rec.Body)
+		}
+	}
+	if len(exceeded) != 1 || exceeded[0] != "/slow" {
+		t.Errorf("OnExceeded calls: %q", exceeded)
+	}
+}
@@ -0,0 +1,101 @@
+package httpspy
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"time"
+)
+
+// A Duplicate describes a response body seen by a Deduplicator.
+type Duplicate struct {
+	// Hash is the hex encoded SHA-256 hash of the body.
+	Hash      string
+	Size      int
+	Count     int64
+	FirstSeen time.Time
+}
+
+// A Deduplicator counts byte-identical response bodies to identify responses
+// that could be cached or collapsed.  A Deduplicator is safe for concurrent
+// use.
+type Deduplicator struct {
+	max  int
+	mut  sync.Mutex
+	seen map[string]*Duplicate
+}
+
+// NewDeduplicator returns a Deduplicator tracking at most max distinct
+// bodies.  When the limit is reached the least duplicated body is forgotten
+// to make room for a new one.
+func NewDeduplicator(max int) *Deduplicator {
+	d := new(Deduplicator)
+	d.max = max
+	d.seen = make(map[string]*Duplicate)
+	return d
+}
+
+// Add records the body captured by s.  Add should only be called after the
+// handler has returned.
+func (d *Deduplicator) Add(s WriteSpy) {
+	body := s.Body()
+	sum := sha256.Sum256(body)
+	hash := hex.EncodeToString(sum[:])
+
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	if dup := d.seen[hash]; dup != nil {
+		dup.Count++
+		return
+	}
+	if len(d.seen) >= d.max {
+		d.evict()
+	}
+	if d.max > 0 {
+		d.seen[hash] = &Duplicate{Hash: hash, Size: len(body), Count: 1, FirstSeen: time.Now()}
+	}
+}
+
+// evict removes the least duplicated body, preferring the oldest.  It must be
+// called with d.mut held.
+func (d *Deduplicator) evict() {
+	var victim *Duplicate
+	for _, dup := range d.seen {
+		if victim == nil || dup.Count < victim.Count ||
+			dup.Count == victim.Count && dup.FirstSeen.Before(victim.FirstSeen) {
+			victim = dup
+		}
+	}
+	if victim != nil {
+		delete(d.seen, victim.Hash)
+	}
+}
+
+// Top returns up to n of the most duplicated bodies, most duplicated first.
+// Bodies seen only once are not duplicates and are omitted.  Nil is returned
+// when n is not positive.
+func (d *Deduplicator) Top(n int) []Duplicate {
+	if n <= 0 {
+		return nil
+	}
+	d.mut.Lock()
+	var dups []Duplicate
+	for _, dup := range d.seen {
+		if dup.Count > 1 {
+			dups = append(dups, *dup)
+		}
+	}
+	d.mut.Unlock()
+
+	sort.Slice(dups, func(i, j int) bool {
+		if dups[i].Count != dups[j].Count {
+			return dups[i].Count > dups[j].Count
+		}
+		return dups[i].FirstSeen.Before(dups[j].FirstSeen)
+	})
+	if len(dups) > n {
+		dups = dups[:n]
+	}
+	return dups
+}
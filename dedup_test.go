@@ -0,0 +1,36 @@
+package httpspy
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDeduplicator(t *testing.T) {
+	d := NewDeduplicator(2)
+	for _, body := range []string{"a", "b", "a", "b", "a", "c", "c"} {
+		s := NewWriteSpy(httptest.NewRecorder())
+		io.WriteString(s, body)
+		d.Add(s)
+	}
+
+	top := d.Top(10)
+	if len(top) != 2 {
+		t.Fatalf("top: %+v", top)
+	}
+	if top[0].Count != 3 || top[0].Size != 1 {
+		t.Errorf("most duplicated: %+v", top[0])
+	}
+	// "b" was evicted to make room for "c".
+	if top[1].Count != 2 || top[1].Hash == top[0].Hash {
+		t.Errorf("second most duplicated: %+v", top[1])
+	}
+	if top := d.Top(1); len(top) != 1 {
+		t.Errorf("top 1: %+v", top)
+	}
+	for _, n := range []int{0, -1} {
+		if top := d.Top(n); top != nil {
+			t.Errorf("top %d: %+v", n, top)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package httpspy
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// EventKind identifies the http.ResponseWriter method recorded by an Event.
+type EventKind int
+
+// The kinds of events recorded by an EventSpy.
+const (
+	EventHeader EventKind = iota
+	EventWriteHeader
+	EventWrite
+)
+
+// An Event records a single call a handler made on an EventSpy.
+type Event struct {
+	Kind EventKind
+	// Code is the argument to WriteHeader for EventWriteHeader events.
+	Code int
+	// N is the number of bytes written for EventWrite events.
+	N int
+}
+
+func (e Event) String() string {
+	switch e.Kind {
+	case EventHeader:
+		return "Header()"
+	case EventWriteHeader:
+		return fmt.Sprintf("WriteHeader(%d)", e.Code)
+	case EventWrite:
+		return fmt.Sprintf("Write(%d)", e.N)
+	}
+	return fmt.Sprintf("Event(%d)", int(e.Kind))
+}
+
+// An EventSpy is a Spy that records the timeline of method calls made on it.
+// Recording has a cost on every call so an EventSpy is meant for debugging.
+type EventSpy interface {
+	Spy
+	// Events returns the calls made on the spy in the order they were made.
+	Events() []Event
+}
+
+// NewEventSpy returns a generic, threadsafe EventSpy implementation.
+func NewEventSpy(w http.ResponseWriter) EventSpy {
+	s := new(eventSpy)
+	s.simpleSpy = new(simpleSpy)
+	s.simpleSpy.w = w
+	return s
+}
+
+type eventSpy struct {
+	*simpleSpy
+	mut    sync.Mutex
+	events []Event
+}
+
+func (s *eventSpy) Header() http.Header {
+	s.mut.Lock()
+	s.events = append(s.events, Event{Kind: EventHeader})
+	h := s.simpleSpy.Header()
+	s.mut.Unlock()
+	return h
+}
+
+func (s *eventSpy) WriteHeader(code int) {
+	s.mut.Lock()
+	s.events = append(s.events, Event{Kind: EventWriteHeader, Code: code})
+	s.simpleSpy.WriteHeader(code)
+	s.mut.Unlock()
+}
+
+func (s *eventSpy) Write(p []byte) (int, error) {
+	s.mut.Lock()
+	n, err := s.simpleSpy.Write(p)
+	s.events = append(s.events, Event{Kind: EventWrite, N: n})
+	s.mut.Unlock()
+	return n, err
+}
+
+func (s *eventSpy) Events() []Event {
+	s.mut.Lock()
+	events := append([]Event(nil), s.events...)
+	s.mut.Unlock()
+	return events
+}
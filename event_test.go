@@ -0,0 +1,20 @@
+package httpspy
+
+import (
+	"fmt"
+	"net/http/httptest"
+)
+
+func ExampleEventSpy() {
+	s := NewEventSpy(httptest.NewRecorder())
+	s.Write([]byte("oops"))
+	s.Header().Set("Content-Type", "text/plain")
+	s.WriteHeader(500)
+	for _, e := range s.Events() {
+		fmt.Println(e)
+	}
+	// Output:
+	// Write(4)
+	// Header()
+	// WriteHeader(500)
+}
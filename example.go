@@ -0,0 +1,93 @@
+package httpspy
+
+import (
+	"net/http"
+	"sync"
+)
+
+// An Example is a response retained by Examples.
+type Example struct {
+	Route  string
+	Code   int
+	Header http.Header
+	Body   []byte
+	// Truncated is true if Body was cut short of the captured body.
+	Truncated bool
+}
+
+// Examples retains one example response per route and status code, for
+// instance to generate API documentation from test traffic.  Examples is safe
+// for concurrent use.
+type Examples struct {
+	maxBody int
+	latest  bool
+	mut     sync.Mutex
+	m       map[string]map[int]*Example
+}
+
+// NewExamples returns an Examples retaining at most maxBody bytes of each
+// body.  A negative maxBody is treated as zero.  If latest is true each example is replaced by later responses with
+// the same route and code, otherwise the first response is kept.
+func NewExamples(maxBody int, latest bool) *Examples {
+	if maxBody < 0 {
+		maxBody = 0
+	}
+	e := new(Examples)
+	e.maxBody = maxBody
+	e.latest = latest
+	e.m = make(map[string]map[int]*Example)
+	return e
+}
+
+// Add records the response observed by s as served by route.  Add should only
+// be called after the handler has returned.
+func (e *Examples) Add(route string, s WriteSpy) {
+	code := s.Code()
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	codes := e.m[route]
+	if codes == nil {
+		codes = make(map[int]*Example)
+		e.m[route] = codes
+	}
+	if codes[code] != nil && !e.latest {
+		return
+	}
+
+	codes[code] = newExample(route, s, e.maxBody)
+}
+
+// newExample copies the response observed by s, keeping at most maxBody bytes
+// of its body.
+func newExample(route string, s WriteSpy, maxBody int) *Example {
+	body := s.Body()
+	ex := &Example{Route: route, Code: s.Code(), Header: cloneHeader(s.Header())}
+	if len(body) > maxBody {
+		body = body[:maxBody]
+		ex.Truncated = true
+	}
+	ex.Body = append([]byte(nil), body...)
+	return ex
+}
+
+// Map returns a copy of the retained examples indexed by route and code.
+func (e *Examples) Map() map[string]map[int]Example {
+	e.mut.Lock()
+	defer e.mut.Unlock()
+	m := make(map[string]map[int]Example, len(e.m))
+	for route, codes := range e.m {
+		m[route] = make(map[int]Example, len(codes))
+		for code, ex := range codes {
+			m[route][code] = *ex
+		}
+	}
+	return m
+}
+
+func cloneHeader(h http.Header) http.Header {
+	h2 := make(http.Header, len(h))
+	for k, v := range h {
+		h2[k] = append([]string(nil), v...)
+	}
+	return h2
+}
@@ -0,0 +1,44 @@
+package httpspy
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestExamples(t *testing.T) {
+	for _, latest := range []bool{false, true} {
+		ex := NewExamples(4, latest)
+		for _, body := range []string{"first", "second"} {
+			s := NewWriteSpy(httptest.NewRecorder())
+			s.Header().Set("Content-Type", "text/plain")
+			io.WriteString(s, body)
+			ex.Add("GET /pets", s)
+		}
+		nf := NewWriteSpy(httptest.NewRecorder())
+		nf.WriteHeader(404)
+		ex.Add("GET /pets", nf)
+
+		m := ex.Map()["GET /pets"]
+		want := "firs"
+		if latest {
+			want = "seco"
+		}
+		if got := m[200]; string(got.Body) != want || !got.Truncated || got.Header.Get("Content-Type") != "text/plain" {
+			t.Errorf("latest=%v: %+v", latest, got)
+		}
+		if got := m[404]; got.Code != 404 || len(got.Body) != 0 || got.Truncated {
+			t.Errorf("latest=%v: %+v", latest, got)
+		}
+	}
+}
+
+func TestExamplesNegativeMaxBody(t *testing.T) {
+	ex := NewExamples(-1, false)
+	s := NewWriteSpy(httptest.NewRecorder())
+	io.WriteString(s, "body")
+	ex.Add("GET /", s)
+	if got := ex.Map()["GET /"][200]; len(got.Body) != 0 || !got.Truncated {
+		t.Errorf("%+v", got)
+	}
+}
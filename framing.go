@@ -0,0 +1,60 @@
+package httpspy
+
+import (
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// framingErrors lists errors indicating that a response violated HTTP
+// framing, as opposed to ordinary I/O failures such as a closed connection.
+// They are the framing errors exported by net/http:
+//
+//	http.ErrBodyNotAllowed  a body was written for a HEAD request, 1xx, 204 or 304
+//	http.ErrContentLength   more bytes were written than the declared Content-Length
+//
+// The HTTP/2 stream errors of net/http are unexported and cannot be matched.
+var framingErrors = []error{
+	http.ErrBodyNotAllowed,
+	http.ErrContentLength,
+}
+
+var (
+	framingMut   sync.RWMutex
+	framingFuncs []func(error) bool
+)
+
+// RegisterFramingError makes IsFramingError classify the errors for which
+// match returns true as framing errors.  Typed errors are matched with
+// errors.As, for example the stream errors of golang.org/x/net/http2:
+//
+//	httpspy.RegisterFramingError(func(err error) bool {
+//		var se http2.StreamError
+//		return errors.As(err, &se)
+//	})
+//
+// RegisterFramingError is safe to call while requests are served.
+func RegisterFramingError(match func(error) bool) {
+	framingMut.Lock()
+	framingFuncs = append(framingFuncs, match)
+	framingMut.Unlock()
+}
+
+// IsFramingError returns true if err matches http.ErrBodyNotAllowed or
+// http.ErrContentLength according to errors.Is, or if a function passed to
+// RegisterFramingError returns true for it.
+func IsFramingError(err error) bool {
+	for _, target := range framingErrors {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	framingMut.RLock()
+	defer framingMut.RUnlock()
+	for _, match := range framingFuncs {
+		if match(err) {
+			return true
+		}
+	}
+	return false
+}
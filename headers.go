@@ -0,0 +1,315 @@
+package httpspy
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// SerializedHeaderSize returns the number of bytes the header of the response
+// observed by s occupies on the wire in HTTP/1.1, including the CRLF ending
+// each field and the empty line ending the block.  The status line and fields
+// added by net/http itself, such as Date and Content-Length, are not counted.
+//
+// The size is computed from s.Header() when SerializedHeaderSize is called.
+// It should only be called after the handler has returned, and changes the
+// handler made to the header after writing the response are counted even
+// though they were never sent.
+func SerializedHeaderSize(s Spy) int {
+	var w countWriter
+	s.Header().Write(&w)
+	return int(w) + len("\r\n")
+}
+
+// HeaderInjectionSuspected returns true if a header key or value of the
+// response observed by s contains a CR or LF, which could split the response.
+// net/http refuses to send such values, so this mainly serves to catch the
+// bug in tests.  Like SerializedHeaderSize it inspects s.Header() as it is
+// when called, after the handler has returned.
+func HeaderInjectionSuspected(s Spy) bool {
+	for k, vs := range s.Header() {
+		if strings.ContainsAny(k, "\r\n") {
+			return true
+		}
+		for _, v := range vs {
+			if strings.ContainsAny(v, "\r\n") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SecurityHeaderSet reports which common security headers a response set.
+type SecurityHeaderSet struct {
+	ContentSecurityPolicy   bool
+	ContentTypeOptions      bool // X-Content-Type-Options
+	StrictTransportSecurity bool
+	FrameOptions            bool // X-Frame-Options
+	ReferrerPolicy          bool
+}
+
+// SecurityHeaders reports which of the Content-Security-Policy,
+// X-Content-Type-Options, Strict-Transport-Security, X-Frame-Options and
+// Referrer-Policy headers the response observed by s set to a non-empty value.
+// The values themselves are not validated.  Like HeaderInjectionSuspected it
+// reads s.Header() after the handler has returned.
+func SecurityHeaders(s Spy) SecurityHeaderSet {
+	h := s.Header()
+	return SecurityHeaderSet{
+		ContentSecurityPolicy:   h.Get("Content-Security-Policy") != "",
+		ContentTypeOptions:      h.Get("X-Content-Type-Options") != "",
+		StrictTransportSecurity: h.Get("Strict-Transport-Security") != "",
+		FrameOptions:            h.Get("X-Frame-Options") != "",
+		ReferrerPolicy:          h.Get("Referrer-Policy") != "",
+	}
+}
+
+// ConnectionClose returns true if the response observed by s set
+// "Connection: close", forcing the connection to be torn down after the
+// response.  It reads s.Header() after the handler has returned.
+func ConnectionClose(s Spy) bool {
+	return headerHasToken(s.Header(), "Connection", "close")
+}
+
+// MissingVary returns true if the response observed by s appears to have been
+// negotiated from a header of req but does not name that header in Vary.  The
+// heuristic is conservative: only content coding is detected, by a
+// Content-Encoding other than identity in response to a request with an
+// Accept-Encoding header.  Negotiation on Accept cannot be told apart from a
+// handler that always serves one type, so it is not flagged.  A "Vary: *"
+// satisfies every header.  The response header is read from s.Header() after
+// the handler has returned.
+func MissingVary(req *http.Request, s Spy) bool {
+	h := s.Header()
+	if headerHasToken(h, "Vary", "*") {
+		return false
+	}
+	ce := strings.TrimSpace(h.Get("Content-Encoding"))
+	negotiated := req.Header.Get("Accept-Encoding") != "" && ce != "" && !strings.EqualFold(ce, "identity")
+	return negotiated && !headerHasToken(h, "Vary", "Accept-Encoding")
+}
+
+// RangeHonored returns true if req carried a Range header and the response
+// observed by s is a 206 (partial content) with a Content-Range header.
+// False is returned for requests without a Range header, so callers should
+// only consult RangeHonored for range requests.  A 416 is not considered
+// honoring the range.  The Content-Range is read from s.Header() after the
+// handler has returned.
+func RangeHonored(req *http.Request, s Spy) bool {
+	return req.Header.Get("Range") != "" &&
+		s.Code() == http.StatusPartialContent &&
+		s.Header().Get("Content-Range") != ""
+}
+
+// ShouldHaveBeen304 returns true if the response observed by s was a 200 with
+// a body although its validators satisfied the conditions of req, so a 304
+// (not modified) could have been sent instead.  Only GET and HEAD requests
+// are considered.  As in RFC 7232, If-None-Match is compared with the ETag
+// using weak comparison and takes precedence over If-Modified-Since, which is
+// compared with Last-Modified.  The validators are read from s.Header() after
+// the handler has returned.
+func ShouldHaveBeen304(req *http.Request, s WriteSpy) bool {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		return false
+	}
+	if s.Code() != http.StatusOK || len(s.Body()) == 0 {
+		return false
+	}
+	h := s.Header()
+	if inm := req.Header.Get("If-None-Match"); inm != "" {
+		etag := h.Get("Etag")
+		if etag == "" {
+			return false
+		}
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			if tag == "*" || weakETagEqual(tag, etag) {
+				return true
+			}
+		}
+		return false
+	}
+	ims, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+	modified, err := http.ParseTime(h.Get("Last-Modified"))
+	return err == nil && !modified.After(ims)
+}
+
+// weakETagEqual compares entity tags a and b ignoring their weak prefixes.
+func weakETagEqual(a, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
+
+// CharsetMismatch returns true if the body captured by s cannot be encoded in
+// the charset declared by the Content-Type of the response.  Only UTF-8 and
+// US-ASCII are checked.  False is returned when no charset is declared, the
+// charset is another one, or the Content-Type cannot be parsed.  The
+// Content-Type is read from s.Header() when CharsetMismatch is called.
+func CharsetMismatch(s WriteSpy) bool {
+	_, params, err := mime.ParseMediaType(s.Header().Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	body := s.Body()
+	switch strings.ToLower(params["charset"]) {
+	case "utf-8", "utf8":
+		return !utf8.Valid(body)
+	case "us-ascii", "ascii":
+		for _, c := range body {
+			if c >= utf8.RuneSelf {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ContentTypeMatchesBody returns false if the body captured by s does not look
+// like the Content-Type the response declared, for example an image/png
+// response whose body is HTML.  The body is sniffed with
+// http.DetectContentType and compared by category, not exactly: textual types
+// (text/*, JSON, XML, JavaScript, SVG) form one category, image, audio, video
+// and font types each form one category by their top-level type, and other
+// types stand alone.  A body sniffed as application/octet-stream matches any
+// non-textual type, and a declared application/octet-stream matches any body.
+// True is returned for an empty body or when no Content-Type is set.  The
+// Content-Type is read from s.Header() when ContentTypeMatchesBody is called.
+func ContentTypeMatchesBody(s WriteSpy) bool {
+	body := s.Body()
+	declared := mediaType(s.Header().Get("Content-Type"))
+	if len(body) == 0 || declared == "" || declared == "application/octet-stream" {
+		return true
+	}
+	sniffed := mediaType(http.DetectContentType(body))
+	if sniffed == "application/octet-stream" {
+		return typeCategory(declared) != "text"
+	}
+	return typeCategory(declared) == typeCategory(sniffed)
+}
+
+// typeCategory returns the category ContentTypeMatchesBody compares media
+// type typ by.
+func typeCategory(typ string) string {
+	if strings.HasPrefix(typ, "text/") || compressibleType(typ) {
+		return "text"
+	}
+	for _, top := range []string{"image/", "audio/", "video/", "font/"} {
+		if strings.HasPrefix(typ, top) {
+			return top
+		}
+	}
+	return typ
+}
+
+// Deprecated returns true if the response observed by s advertised that its
+// endpoint is deprecated with a Deprecation header (RFC 9745), and the time
+// given by its Sunset header (RFC 8594).  The Deprecation value may be a
+// structured date such as "@1688169599", or "true" or an HTTP date as in
+// earlier drafts.  The zero time is returned when Sunset is absent or
+// malformed.  Both headers are read from s.Header() when Deprecated is called.
+func Deprecated(s Spy) (bool, time.Time) {
+	h := s.Header()
+	var deprecated bool
+	if v := strings.TrimSpace(h.Get("Deprecation")); strings.HasPrefix(v, "@") {
+		_, err := strconv.ParseInt(v[1:], 10, 64)
+		deprecated = err == nil
+	} else if v == "true" {
+		deprecated = true
+	} else if _, err := http.ParseTime(v); err == nil {
+		deprecated = true
+	}
+	sunset, err := http.ParseTime(strings.TrimSpace(h.Get("Sunset")))
+	if err != nil {
+		sunset = time.Time{}
+	}
+	return deprecated, sunset
+}
+
+// ValidETag returns false if the response observed by s has an ETag header
+// that is not a single entity tag as defined by RFC 7232: a double quoted
+// string, optionally prefixed by W/ for a weak tag, without spaces, quotes or
+// control characters inside.  True is returned when no ETag is set.  The ETag
+// is read from s.Header() when ValidETag is called.
+func ValidETag(s Spy) bool {
+	vs := s.Header()["Etag"]
+	switch len(vs) {
+	case 0:
+		return true
+	case 1:
+		return validETag(vs[0])
+	}
+	return false
+}
+
+func validETag(tag string) bool {
+	tag = strings.TrimPrefix(tag, "W/")
+	if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+		return false
+	}
+	for i := 1; i < len(tag)-1; i++ {
+		// etagc = %x21 / %x23-7E / obs-text
+		if c := tag[i]; c < 0x21 || c == '"' || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+// A CSPAudit flags weak directives in a Content-Security-Policy.  The zero
+// value flags the 'unsafe-inline', 'unsafe-eval' and * (wildcard) sources.
+type CSPAudit struct {
+	// WeakSources lists the source expressions that make a directive weak,
+	// compared case insensitively.  If nil the defaults are used.
+	WeakSources []string
+}
+
+var defaultWeakCSPSources = []string{"'unsafe-inline'", "'unsafe-eval'", "*"}
+
+// WeakCSPDirectives is equivalent to CSPAudit{}.WeakDirectives(s).
+func WeakCSPDirectives(s Spy) []string {
+	return CSPAudit{}.WeakDirectives(s)
+}
+
+// WeakDirectives returns each directive of the Content-Security-Policy of the
+// response observed by s that allows a weak source, followed by that source,
+// as in "script-src 'unsafe-inline'".  Directives are reported in policy order
+// and nil is returned when the policy has no weak directive or none was set.
+// The policy is read from s.Header() when WeakDirectives is called.
+func (a CSPAudit) WeakDirectives(s Spy) []string {
+	weak := a.WeakSources
+	if weak == nil {
+		weak = defaultWeakCSPSources
+	}
+	var found []string
+	for _, policy := range s.Header()["Content-Security-Policy"] {
+		for _, directive := range strings.Split(policy, ";") {
+			fields := strings.Fields(directive)
+			if len(fields) == 0 {
+				continue
+			}
+			name := strings.ToLower(fields[0])
+			for _, src := range fields[1:] {
+				for _, w := range weak {
+					if strings.EqualFold(src, w) {
+						found = append(found, name+" "+w)
+					}
+				}
+			}
+		}
+	}
+	return found
+}
+
+// countWriter counts the bytes written to it.
+type countWriter int64
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	*w += countWriter(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,328 @@
+package httpspy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSerializedHeaderSize(t *testing.T) {
+	s := NewSpy(httptest.NewRecorder())
+	if n := SerializedHeaderSize(s); n != 2 {
+		t.Errorf("empty header: %d", n)
+	}
+	s.Header().Set("Content-Type", "text/plain")
+	s.Header().Add("X-Trace", "a")
+	s.Header().Add("X-Trace", "bc")
+	s.WriteHeader(200)
+	want := len("Content-Type: text/plain\r\nX-Trace: a\r\nX-Trace: bc\r\n\r\n")
+	if n := SerializedHeaderSize(s); n != want {
+		t.Errorf("size %d != %d", n, want)
+	}
+}
+
+func TestHeaderInjectionSuspected(t *testing.T) {
+	for _, test := range []struct {
+		key, value string
+		suspect    bool
+	}{
+		{"Location", "/next", false},
+		{"Location", "/next\r\nSet-Cookie: a=b", true},
+		{"X-Name", "a\nb", true},
+		{"X-Bad\r\nKey", "v", true},
+	} {
+		s := NewSpy(httptest.NewRecorder())
+		s.Header()[test.key] = []string{test.value}
+		if HeaderInjectionSuspected(s) != test.suspect {
+			t.Errorf("%q: %q: suspected=%v", test.key, test.value, !test.suspect)
+		}
+	}
+}
+
+func TestCharsetMismatch(t *testing.T) {
+	for _, test := range []struct {
+		ctype    string
+		body     string
+		mismatch bool
+	}{
+		{"text/plain; charset=utf-8", "héllo", false},
+		{"text/plain; charset=UTF-8", "h\xe9llo", true},
+		{"text/plain; charset=us-ascii", "héllo", true},
+		{"text/plain; charset=us-ascii", "hello", false},
+		{"text/plain; charset=iso-8859-1", "h\xe9llo", false},
+		{"text/plain", "h\xe9llo", false},
+	} {
+		s := NewWriteSpy(httptest.NewRecorder())
+		s.Header().Set("Content-Type", test.ctype)
+		s.Write([]byte(test.body))
+		if CharsetMismatch(s) != test.mismatch {
+			t.Errorf("%q %q: mismatch=%v", test.ctype, test.body, !test.mismatch)
+		}
+	}
+}
+
+func TestDeprecated(t *testing.T) {
+	sunset := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for _, test := range []struct {
+		deprecation string
+		sunset      string
+		deprecated  bool
+		at          time.Time
+	}{
+		{"", "", false, time.Time{}},
+		{"@1688169599", "Tue, 01 Jan 2030 00:00:00 GMT", true, sunset},
+		{"true", "", true, time.Time{}},
+		{"Sun, 11 Nov 2018 23:59:59 GMT", "soon", true, time.Time{}},
+		{"false", "Tue, 01 Jan 2030 00:00:00 GMT", false, sunset},
+		{"@soon", "", false, time.Time{}},
+	} {
+		s := NewSpy(httptest.NewRecorder())
+		if test.deprecation != "" {
+			s.Header().Set("Deprecation", test.deprecation)
+		}
+		if test.sunset != "" {
+			s.Header().Set("Sunset", test.sunset)
+		}
+		deprecated, at := Deprecated(s)
+		if deprecated != test.deprecated || !at.Equal(test.at) {
+			t.Errorf("%q %q: deprecated=%v sunset=%v", test.deprecation, test.sunset, deprecated, at)
+		}
+	}
+}
+
+func TestValidETag(t *testing.T) {
+	for _, test := range []struct {
+		etag  []string
+		valid bool
+	}{
+		{nil, true},
+		{[]string{`"abc"`}, true},
+		{[]string{`W/"abc"`}, true},
+		{[]string{`""`}, true},
+		{[]string{`abc`}, false},
+		{[]string{`w/"abc"`}, false},
+		{[]string{`"a"b"`}, false},
+		{[]string{`"a b"`}, false},
+		{[]string{`"a"`, `"b"`}, false},
+	} {
+		s := NewSpy(httptest.NewRecorder())
+		s.Header()["Etag"] = test.etag
+		if ValidETag(s) != test.valid {
+			t.Errorf("%q: valid=%v", test.etag, !test.valid)
+		}
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	for _, test := range []struct {
+		header map[string]string
+		want   SecurityHeaderSet
+	}{
+		{nil, SecurityHeaderSet{}},
+		{
+			map[string]string{
+				"Content-Security-Policy":   "default-src 'self'",
+				"X-Content-Type-Options":    "nosniff",
+				"Strict-Transport-Security": "max-age=63072000",
+				"X-Frame-Options":           "DENY",
+				"Referrer-Policy":           "no-referrer",
+			},
+			SecurityHeaderSet{true, true, true, true, true},
+		},
+		{
+			map[string]string{"X-Content-Type-Options": "nosniff", "Referrer-Policy": ""},
+			SecurityHeaderSet{ContentTypeOptions: true},
+		},
+	} {
+		s := NewSpy(httptest.NewRecorder())
+		for k, v := range test.header {
+			s.Header().Set(k, v)
+		}
+		if got := SecurityHeaders(s); got != test.want {
+			t.Errorf("%v: %+v", test.header, got)
+		}
+	}
+}
+
+func TestConnectionClose(t *testing.T) {
+	for _, test := range []struct {
+		connection []string
+		close      bool
+	}{
+		{nil, false},
+		{[]string{"keep-alive"}, false},
+		{[]string{"close"}, true},
+		{[]string{"Upgrade, Close"}, true},
+	} {
+		s := NewSpy(httptest.NewRecorder())
+		s.Header()["Connection"] = test.connection
+		if ConnectionClose(s) != test.close {
+			t.Errorf("%q: close=%v", test.connection, !test.close)
+		}
+	}
+}
+
+func TestContentTypeMatchesBody(t *testing.T) {
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	for _, test := range []struct {
+		ctype string
+		body  string
+		match bool
+	}{
+		{"image/png", "<!DOCTYPE html><html></html>", false},
+		{"image/png", png, true},
+		{"image/jpeg", png, true},
+		{"text/html; charset=utf-8", png, false},
+		{"application/json", `{"ok":true}`, true},
+		{"text/plain", "\x00\x01\x02", false},
+		{"application/x-custom", "\x00\x01\x02", true},
+		{"application/octet-stream", "<html></html>", true},
+		{"application/pdf", "%PDF-1.7", true},
+		{"application/pdf", "hello", false},
+		{"", "hello", true},
+		{"image/png", "", true},
+	} {
+		s := NewWriteSpy(httptest.NewRecorder())
+		if test.ctype != "" {
+			s.Header().Set("Content-Type", test.ctype)
+		}
+		s.Write([]byte(test.body))
+		if ContentTypeMatchesBody(s) != test.match {
+			t.Errorf("%q %q: match=%v", test.ctype, test.body, !test.match)
+		}
+	}
+}
+
+func TestWeakCSPDirectives(t *testing.T) {
+	for _, test := range []struct {
+		audit  CSPAudit
+		policy []string
+		weak   []string
+	}{
+		{CSPAudit{}, nil, nil},
+		{CSPAudit{}, []string{"default-src 'self'; img-src https://cdn.example.com"}, nil},
+		{
+			CSPAudit{},
+			[]string{"default-src 'self'; Script-Src 'self' 'UNSAFE-INLINE' 'unsafe-eval'; img-src *"},
+			[]string{"script-src 'unsafe-inline'", "script-src 'unsafe-eval'", "img-src *"},
+		},
+		{
+			CSPAudit{WeakSources: []string{"data:"}},
+			[]string{"img-src * data:", "script-src 'unsafe-inline'"},
+			[]string{"img-src data:"},
+		},
+	} {
+		s := NewSpy(httptest.NewRecorder())
+		s.Header()["Content-Security-Policy"] = test.policy
+		if weak := test.audit.WeakDirectives(s); !reflect.DeepEqual(weak, test.weak) {
+			t.Errorf("%q: %q", test.policy, weak)
+		}
+	}
+}
+
+func TestMissingVary(t *testing.T) {
+	for _, test := range []struct {
+		accept   string
+		encoding string
+		vary     string
+		missing  bool
+	}{
+		{"gzip", "gzip", "", true},
+		{"gzip", "gzip", "Accept, accept-encoding", false},
+		{"gzip", "gzip", "*", false},
+		{"gzip", "", "", false},
+		{"gzip", "identity", "", false},
+		{"", "gzip", "", false},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		if test.accept != "" {
+			req.Header.Set("Accept-Encoding", test.accept)
+		}
+		s := NewSpy(httptest.NewRecorder())
+		if test.encoding != "" {
+			s.Header().Set("Content-Encoding", test.encoding)
+		}
+		if test.vary != "" {
+			s.Header().Set("Vary", test.vary)
+		}
+		if MissingVary(req, s) != test.missing {
+			t.Errorf("%q %q %q: missing=%v", test.accept, test.encoding, test.vary, !test.missing)
+		}
+	}
+}
+
+func TestRangeHonored(t *testing.T) {
+	for _, test := range []struct {
+		rng     string
+		code    int
+		crange  string
+		honored bool
+	}{
+		{"bytes=0-9", http.StatusPartialContent, "bytes 0-9/100", true},
+		{"bytes=0-9", http.StatusOK, "", false},
+		{"bytes=0-9", http.StatusPartialContent, "", false},
+		{"", http.StatusPartialContent, "bytes 0-9/100", false},
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		if test.rng != "" {
+			req.Header.Set("Range", test.rng)
+		}
+		s := NewSpy(httptest.NewRecorder())
+		if test.crange != "" {
+			s.Header().Set("Content-Range", test.crange)
+		}
+		s.WriteHeader(test.code)
+		if RangeHonored(req, s) != test.honored {
+			t.Errorf("%q %d %q: honored=%v", test.rng, test.code, test.crange, !test.honored)
+		}
+	}
+}
+
+func TestShouldHaveBeen304(t *testing.T) {
+	const (
+		older = "Sun, 06 Nov 1994 08:49:37 GMT"
+		newer = "Mon, 07 Nov 1994 08:49:37 GMT"
+	)
+	for _, test := range []struct {
+		method   string
+		inm, ims string
+		etag, lm string
+		code     int
+		should   bool
+	}{
+		{"GET", `"a"`, "", `"a"`, "", 200, true},
+		{"GET", `"b", W/"a"`, "", `"a"`, "", 200, true},
+		{"GET", `*`, "", `"a"`, "", 200, true},
+		{"GET", `"b"`, "", `"a"`, "", 200, false},
+		{"GET", `"a"`, "", "", "", 200, false},
+		{"GET", `"b"`, newer, `"a"`, older, 200, false},
+		{"GET", "", newer, "", older, 200, true},
+		{"GET", "", older, "", older, 200, true},
+		{"GET", "", older, "", newer, 200, false},
+		{"GET", "", "", `"a"`, older, 200, false},
+		{"GET", `"a"`, "", `"a"`, "", 404, false},
+		{"POST", `"a"`, "", `"a"`, "", 200, false},
+	} {
+		req := httptest.NewRequest(test.method, "/", nil)
+		if test.inm != "" {
+			req.Header.Set("If-None-Match", test.inm)
+		}
+		if test.ims != "" {
+			req.Header.Set("If-Modified-Since", test.ims)
+		}
+		s := NewWriteSpy(httptest.NewRecorder())
+		if test.etag != "" {
+			s.Header().Set("ETag", test.etag)
+		}
+		if test.lm != "" {
+			s.Header().Set("Last-Modified", test.lm)
+		}
+		s.WriteHeader(test.code)
+		s.Write([]byte("body"))
+		if ShouldHaveBeen304(req, s) != test.should {
+			t.Errorf("%+v: should=%v", test, !test.should)
+		}
+	}
+}
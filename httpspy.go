@@ -7,6 +7,7 @@ package httpspy
 
 import (
 	"bytes"
+	"math"
 	"net/http"
 	"sync"
 )
@@ -37,6 +38,12 @@ type WriteSpy interface {
 	Body() []byte
 	// WriteErr returns the first error returned by Write() if any.
 	WriteErr() error
+	// FramingErr returns the first error returned by Write() that
+	// IsFramingError classifies as a protocol framing error, if any.
+	FramingErr() error
+	// Entropy returns the Shannon entropy of Body() in bits per byte, between
+	// 0 and 8.  Zero is returned for an empty body.
+	Entropy() float64
 }
 
 // NewWriteSpy returns a generic, threadsafe Spy implementation.  If w is nil
@@ -93,6 +100,13 @@ type simpleWriteSpy struct {
 	mut sync.Mutex
 	buf bytes.Buffer
 	err error
+
+	framingErr error
+
+	// entropy caches the result of Entropy for the first entropyLen bytes
+	// of buf.
+	entropy    float64
+	entropyLen int
 }
 
 func (s *simpleWriteSpy) Write(p []byte) (int, error) {
@@ -101,11 +115,13 @@ func (s *simpleWriteSpy) Write(p []byte) (int, error) {
 	if n > 0 {
 		s.buf.Write(p[:n])
 	}
-	s.mut.Unlock()
-
 	if err != nil && s.err == nil {
 		s.err = err
 	}
+	if err != nil && s.framingErr == nil && IsFramingError(err) {
+		s.framingErr = err
+	}
+	s.mut.Unlock()
 	return n, err
 }
 
@@ -117,5 +133,44 @@ func (s *simpleWriteSpy) Body() []byte {
 }
 
 func (s *simpleWriteSpy) WriteErr() error {
-	return s.err
+	s.mut.Lock()
+	err := s.err
+	s.mut.Unlock()
+	return err
+}
+
+func (s *simpleWriteSpy) FramingErr() error {
+	s.mut.Lock()
+	err := s.framingErr
+	s.mut.Unlock()
+	return err
+}
+
+func (s *simpleWriteSpy) Entropy() float64 {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.buf.Len() != s.entropyLen {
+		s.entropy = entropy(s.buf.Bytes())
+		s.entropyLen = s.buf.Len()
+	}
+	return s.entropy
+}
+
+// entropy returns the Shannon entropy of p in bits per byte.
+func entropy(p []byte) float64 {
+	if len(p) == 0 {
+		return 0
+	}
+	var freq [256]int
+	for _, c := range p {
+		freq[c]++
+	}
+	var h float64
+	for _, n := range freq {
+		if n > 0 {
+			f := float64(n) / float64(len(p))
+			h -= f * math.Log2(f)
+		}
+	}
+	return h
 }
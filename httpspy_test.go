@@ -0,0 +1,101 @@
+package httpspy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWriteSpyEntropy(t *testing.T) {
+	s := NewWriteSpy(httptest.NewRecorder())
+	if h := s.Entropy(); h != 0 {
+		t.Errorf("empty body entropy %v", h)
+	}
+	io.WriteString(s, "aaaa")
+	if h := s.Entropy(); h != 0 {
+		t.Errorf("uniform body entropy %v", h)
+	}
+	io.WriteString(s, "bbbb")
+	if h := s.Entropy(); h != 1 {
+		t.Errorf("two symbol body entropy %v", h)
+	}
+
+	var p [256]byte
+	for i := range p {
+		p[i] = byte(i)
+	}
+	s = NewWriteSpy(httptest.NewRecorder())
+	s.Write(p[:])
+	if h := s.Entropy(); h != 8 {
+		t.Errorf("random body entropy %v", h)
+	}
+}
+
+// errWriter fails all writes with err.
+type errWriter struct {
+	http.ResponseWriter
+	err error
+}
+
+func (w errWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
+
+func TestWriteSpyFramingErr(t *testing.T) {
+	for _, test := range []struct {
+		err     error
+		framing bool
+	}{
+		{io.ErrClosedPipe, false},
+		{http.ErrHijacked, false},
+		{http.ErrContentLength, true},
+		{fmt.Errorf("writing: %w", http.ErrBodyNotAllowed), true},
+	} {
+		s := NewWriteSpy(errWriter{httptest.NewRecorder(), test.err})
+		s.Write([]byte("x"))
+		if s.WriteErr() != test.err {
+			t.Errorf("%v: WriteErr %v", test.err, s.WriteErr())
+		}
+		if got := s.FramingErr(); (got != nil) != test.framing {
+			t.Errorf("%v: FramingErr %v", test.err, got)
+		}
+	}
+}
+
+// streamErr is a typed error like the stream errors of x/net/http2.
+type streamErr struct {
+	ID uint32
+}
+
+func (e streamErr) Error() string {
+	return fmt.Sprintf("stream %d reset", e.ID)
+}
+
+func TestRegisterFramingError(t *testing.T) {
+	framingMut.Lock()
+	saved := framingFuncs
+	framingMut.Unlock()
+	defer func() {
+		framingMut.Lock()
+		framingFuncs = saved
+		framingMut.Unlock()
+	}()
+
+	err := fmt.Errorf("write: %w", streamErr{ID: 5})
+	if IsFramingError(err) {
+		t.Fatalf("unregistered error is a framing error")
+	}
+	RegisterFramingError(func(err error) bool {
+		var se streamErr
+		return errors.As(err, &se)
+	})
+	if !IsFramingError(err) {
+		t.Errorf("registered error is not a framing error")
+	}
+	if IsFramingError(io.ErrClosedPipe) {
+		t.Errorf("unrelated error is a framing error")
+	}
+}
@@ -0,0 +1,198 @@
+package httpspy
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A LabelSpy is a Spy that carries application metadata, such as an
+// experiment variant, along with the response it observes.
+type LabelSpy interface {
+	Spy
+	// SetLabel associates value with key, replacing any previous value.
+	SetLabel(key, value string)
+	// Label returns the value associated with key or the empty string.
+	Label(key string) string
+	// BytesWritten returns the number of body bytes successfully written.
+	BytesWritten() int64
+}
+
+// NewLabelSpy returns a generic, threadsafe LabelSpy implementation.  No
+// labels are set initially.
+func NewLabelSpy(w http.ResponseWriter) LabelSpy {
+	s := new(labelSpy)
+	s.simpleSpy = new(simpleSpy)
+	s.simpleSpy.w = w
+	return s
+}
+
+type labelSpy struct {
+	*simpleSpy
+	mut    sync.Mutex
+	labels map[string]string
+	n      int64
+}
+
+func (s *labelSpy) Write(p []byte) (int, error) {
+	n, err := s.simpleSpy.Write(p)
+	s.mut.Lock()
+	s.n += int64(n)
+	s.mut.Unlock()
+	return n, err
+}
+
+func (s *labelSpy) SetLabel(key, value string) {
+	s.mut.Lock()
+	if s.labels == nil {
+		s.labels = make(map[string]string)
+	}
+	s.labels[key] = value
+	s.mut.Unlock()
+}
+
+func (s *labelSpy) Label(key string) string {
+	s.mut.Lock()
+	v := s.labels[key]
+	s.mut.Unlock()
+	return v
+}
+
+func (s *labelSpy) BytesWritten() int64 {
+	s.mut.Lock()
+	n := s.n
+	s.mut.Unlock()
+	return n
+}
+
+// LabelStats summarizes the responses added to a LabelAggregator that share
+// a tuple of label values.
+type LabelStats struct {
+	// Values holds the label values in the order of the aggregator's keys.
+	Values       []string
+	Count        int64
+	Codes        map[int]int64
+	Bytes        int64
+	TotalLatency time.Duration
+	MaxLatency   time.Duration
+	// Overflow is true for the group collecting responses beyond the
+	// aggregator's group limit.
+	Overflow bool
+}
+
+// OtherLabel is the label value used for every key of the group collecting
+// responses beyond a LabelAggregator's group limit.
+const OtherLabel = "(other)"
+
+// RegionLabel is the conventional label key for the region serving a
+// response.
+const RegionLabel = "region"
+
+// A LabelAggregator groups response statistics by the values spies hold for
+// a fixed set of label keys.  A LabelAggregator is safe for concurrent use.
+type LabelAggregator struct {
+	keys  []string
+	mut   sync.Mutex
+	max   int
+	stats map[string]*LabelStats
+	other *LabelStats // overflow group, kept apart from real tuples
+}
+
+// NewLabelAggregator returns a LabelAggregator grouping spies by their values
+// for keys.  Spies missing a label are grouped under the empty string.
+func NewLabelAggregator(keys ...string) *LabelAggregator {
+	a := new(LabelAggregator)
+	a.keys = append([]string(nil), keys...)
+	a.stats = make(map[string]*LabelStats)
+	return a
+}
+
+// NewRegionAggregator returns a LabelAggregator grouping spies by their
+// RegionLabel, tracking at most max regions.
+func NewRegionAggregator(max int) *LabelAggregator {
+	a := NewLabelAggregator(RegionLabel)
+	a.SetMaxGroups(max)
+	return a
+}
+
+// SetMaxGroups bounds the memory used by a to n label tuples plus one overflow
+// group.  Once n tuples are tracked, responses with new tuples are counted in
+// the overflow group, whose values are all OtherLabel and whose Overflow field
+// is true.  Responses actually labeled OtherLabel are not merged into it.  A
+// non-positive n removes the bound.
+func (a *LabelAggregator) SetMaxGroups(n int) {
+	a.mut.Lock()
+	a.max = n
+	a.mut.Unlock()
+}
+
+// Add records the response observed by s, which took latency to serve.  Add
+// should only be called after the handler has returned.
+func (a *LabelAggregator) Add(s LabelSpy, latency time.Duration) {
+	values := make([]string, len(a.keys))
+	for i, k := range a.keys {
+		values[i] = s.Label(k)
+	}
+	code, n := s.Code(), s.BytesWritten()
+	key := strings.Join(values, "\x00")
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	st := a.stats[key]
+	if st == nil && a.max > 0 && len(a.stats) >= a.max {
+		if a.other == nil {
+			for i := range values {
+				values[i] = OtherLabel
+			}
+			a.other = &LabelStats{Values: values, Codes: make(map[int]int64), Overflow: true}
+		}
+		st = a.other
+	}
+	if st == nil {
+		st = &LabelStats{Values: values, Codes: make(map[int]int64)}
+		a.stats[key] = st
+	}
+	st.Count++
+	st.Codes[code]++
+	st.Bytes += n
+	st.TotalLatency += latency
+	if latency > st.MaxLatency {
+		st.MaxLatency = latency
+	}
+}
+
+// Stats returns a copy of the statistics for each label tuple seen, sorted
+// by label values.  The overflow group, if any, is last.
+func (a *LabelAggregator) Stats() []LabelStats {
+	a.mut.Lock()
+	stats := make([]LabelStats, 0, len(a.stats)+1)
+	for _, st := range a.stats {
+		stats = append(stats, st.clone())
+	}
+	other := a.other
+	if other != nil {
+		stats = append(stats, other.clone())
+	}
+	a.mut.Unlock()
+
+	tuples := stats
+	if other != nil {
+		tuples = stats[:len(stats)-1]
+	}
+	sort.Slice(tuples, func(i, j int) bool {
+		return strings.Join(tuples[i].Values, "\x00") < strings.Join(tuples[j].Values, "\x00")
+	})
+	return stats
+}
+
+func (st *LabelStats) clone() LabelStats {
+	cp := *st
+	cp.Values = append([]string(nil), st.Values...)
+	cp.Codes = make(map[int]int64, len(st.Codes))
+	for code, n := range st.Codes {
+		cp.Codes[code] = n
+	}
+	return cp
+}
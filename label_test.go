@@ -0,0 +1,67 @@
+package httpspy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLabelAggregator(t *testing.T) {
+	agg := NewLabelAggregator("variant")
+	for i, variant := range []string{"a", "b", "a", ""} {
+		s := NewLabelSpy(httptest.NewRecorder())
+		if variant != "" {
+			s.SetLabel("variant", variant)
+		}
+		if i == 2 {
+			s.WriteHeader(http.StatusInternalServerError)
+		}
+		s.Write([]byte("hello"))
+		agg.Add(s, time.Duration(i+1)*time.Millisecond)
+	}
+
+	stats := agg.Stats()
+	if len(stats) != 3 {
+		t.Fatalf("expected 3 groups, got %d", len(stats))
+	}
+	a := stats[1]
+	if a.Values[0] != "a" {
+		t.Fatalf("unexpected group order: %q", a.Values)
+	}
+	if a.Count != 2 || a.Bytes != 10 {
+		t.Errorf("count=%d bytes=%d", a.Count, a.Bytes)
+	}
+	if a.Codes[http.StatusOK] != 1 || a.Codes[http.StatusInternalServerError] != 1 {
+		t.Errorf("codes: %v", a.Codes)
+	}
+	if a.TotalLatency != 4*time.Millisecond || a.MaxLatency != 3*time.Millisecond {
+		t.Errorf("total=%v max=%v", a.TotalLatency, a.MaxLatency)
+	}
+}
+
+func TestRegionAggregator(t *testing.T) {
+	agg := NewRegionAggregator(2)
+	for _, region := range []string{"us-east", OtherLabel, "ap-south", "us-east", "sa-east"} {
+		s := NewLabelSpy(httptest.NewRecorder())
+		s.SetLabel(RegionLabel, region)
+		s.Write([]byte("x"))
+		agg.Add(s, time.Millisecond)
+	}
+
+	// Two tracked regions, one of them really labeled OtherLabel, plus the
+	// overflow group.
+	stats := agg.Stats()
+	if len(stats) != 3 {
+		t.Fatalf("stats: %+v", stats)
+	}
+	if st := stats[0]; st.Values[0] != OtherLabel || st.Overflow || st.Count != 1 {
+		t.Errorf("labeled group: %+v", st)
+	}
+	if st := stats[1]; st.Values[0] != "us-east" || st.Count != 2 {
+		t.Errorf("tracked group: %+v", st)
+	}
+	if st := stats[2]; st.Values[0] != OtherLabel || !st.Overflow || st.Count != 2 {
+		t.Errorf("overflow group: %+v", st)
+	}
+}
@@ -0,0 +1,86 @@
+package httpspy
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultLogBodyLen is the length bodies are truncated to by a BodyLogFormat
+// with a zero MaxLen.
+const DefaultLogBodyLen = 1024
+
+// A BodyLogFormat renders captured bodies as human-friendly strings for logs.
+// The zero value pretty-prints JSON, hex-dumps binary bodies and truncates
+// the result to DefaultLogBodyLen bytes.
+type BodyLogFormat struct {
+	// MaxLen is the maximum length of formatted bodies, excluding a short
+	// truncation notice.  Zero means DefaultLogBodyLen.
+	MaxLen int
+	// Format, if not nil, replaces the default formatting.  Its result is
+	// used without truncation.
+	Format func(body []byte, contentType string) string
+}
+
+// BodyForLog formats the body captured by s using the response Content-Type,
+// or a sniffed type when none was set.
+func (f BodyLogFormat) BodyForLog(s WriteSpy) string {
+	body := s.Body()
+	ct := s.Header().Get("Content-Type")
+	if f.Format != nil {
+		return f.Format(body, ct)
+	}
+	max := f.MaxLen
+	if max <= 0 {
+		max = DefaultLogBodyLen
+	}
+	return formatLogBody(body, ct, max)
+}
+
+func formatLogBody(body []byte, ct string, max int) string {
+	if len(body) == 0 {
+		return ""
+	}
+	if ct == "" {
+		ct = http.DetectContentType(body)
+	}
+	typ := mediaType(ct)
+
+	if (typ == "application/json" || strings.HasSuffix(typ, "+json")) && len(body) <= max {
+		var buf bytes.Buffer
+		if json.Indent(&buf, body, "", "  ") == nil {
+			return truncateLogBody(buf.String(), max, len(body))
+		}
+	}
+	if (strings.HasPrefix(typ, "text/") || compressibleType(typ)) && utf8.Valid(body) {
+		return truncateLogBody(string(body), max, len(body))
+	}
+
+	// Each 16 byte line of a hex dump is about 80 bytes long.
+	n := len(body)
+	if n > max/5 {
+		n = max / 5
+	}
+	dump := hex.Dump(body[:n])
+	if n < len(body) {
+		dump += fmt.Sprintf("... (%d bytes total)", len(body))
+	}
+	return dump
+}
+
+// truncateLogBody truncates s to at most max bytes without splitting a UTF-8
+// sequence, noting the original size when s is cut.
+func truncateLogBody(s string, max, size int) string {
+	if len(s) <= max {
+		return s
+	}
+	i := max
+	for i > 0 && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return fmt.Sprintf("%s... (%d bytes total)", s[:i], size)
+}
@@ -0,0 +1,45 @@
+package httpspy
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBodyLogFormat(t *testing.T) {
+	for _, test := range []struct {
+		ctype string
+		body  string
+		max   int
+		out   string
+	}{
+		{"application/json", `{"id":1}`, 0, "{\n  \"id\": 1\n}"},
+		{"text/plain", "hello, world", 5, "hello... (12 bytes total)"},
+		{"text/plain", "héllo", 2, "h... (6 bytes total)"},
+		{"", "plain text", 0, "plain text"},
+		{"application/octet-stream", "\x00\x01", 0, "00000000  00 01                                             |..|\n"},
+	} {
+		s := NewWriteSpy(httptest.NewRecorder())
+		if test.ctype != "" {
+			s.Header().Set("Content-Type", test.ctype)
+		}
+		s.Write([]byte(test.body))
+		if out := (BodyLogFormat{MaxLen: test.max}).BodyForLog(s); out != test.out {
+			t.Errorf("%q %q: %q", test.ctype, test.body, out)
+		}
+	}
+
+	s := NewWriteSpy(httptest.NewRecorder())
+	s.Write(make([]byte, 4096))
+	out := BodyLogFormat{}.BodyForLog(s)
+	if !strings.HasSuffix(out, "... (4096 bytes total)") || len(out) > DefaultLogBodyLen+32 {
+		t.Errorf("large binary body: %d bytes", len(out))
+	}
+
+	f := BodyLogFormat{Format: func(body []byte, ct string) string { return ct }}
+	s = NewWriteSpy(httptest.NewRecorder())
+	s.Header().Set("Content-Type", "image/png")
+	if out := f.BodyForLog(s); out != "image/png" {
+		t.Errorf("custom format: %q", out)
+	}
+}
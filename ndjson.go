@@ -0,0 +1,71 @@
+package httpspy
+
+import (
+	"net/http"
+	"sync"
+)
+
+// An NDJSONSpy is a Spy that counts the records of a newline-delimited JSON
+// (NDJSON) response body as it streams.
+type NDJSONSpy interface {
+	Spy
+	// RecordCount returns the number of complete records written.  A record
+	// is complete once its terminating newline is written.  Blank lines are
+	// not records.
+	RecordCount() int
+	// TrailingRecord returns true if bytes of a record have been written
+	// without a terminating newline.  When the handler has returned, this
+	// indicates a final record that RecordCount does not include.
+	TrailingRecord() bool
+}
+
+// NewNDJSONSpy returns a generic, threadsafe NDJSONSpy.  Records are
+// delimited by newlines only; their contents are not validated as JSON.
+func NewNDJSONSpy(w http.ResponseWriter) NDJSONSpy {
+	s := new(ndjsonSpy)
+	s.simpleSpy = new(simpleSpy)
+	s.simpleSpy.w = w
+	return s
+}
+
+type ndjsonSpy struct {
+	*simpleSpy
+	mut     sync.Mutex
+	records int
+	// inRecord is true when non-whitespace bytes have been written since the
+	// last newline.
+	inRecord bool
+}
+
+func (s *ndjsonSpy) Write(p []byte) (int, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	n, err := s.simpleSpy.Write(p)
+	for _, c := range p[:n] {
+		switch c {
+		case '\n':
+			if s.inRecord {
+				s.records++
+				s.inRecord = false
+			}
+		case ' ', '\t', '\r':
+		default:
+			s.inRecord = true
+		}
+	}
+	return n, err
+}
+
+func (s *ndjsonSpy) RecordCount() int {
+	s.mut.Lock()
+	n := s.records
+	s.mut.Unlock()
+	return n
+}
+
+func (s *ndjsonSpy) TrailingRecord() bool {
+	s.mut.Lock()
+	ok := s.inRecord
+	s.mut.Unlock()
+	return ok
+}
@@ -0,0 +1,24 @@
+package httpspy
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNDJSONSpy(t *testing.T) {
+	s := NewNDJSONSpy(httptest.NewRecorder())
+	for _, chunk := range []string{`{"id":1}`, "\n{\"id\"", ":2}\n\n  \r\n", `{"id":3}`} {
+		io.WriteString(s, chunk)
+	}
+	if n := s.RecordCount(); n != 2 {
+		t.Errorf("%d records", n)
+	}
+	if !s.TrailingRecord() {
+		t.Errorf("trailing record not reported")
+	}
+	io.WriteString(s, "\n")
+	if n := s.RecordCount(); n != 3 || s.TrailingRecord() {
+		t.Errorf("%d records, trailing %v", n, s.TrailingRecord())
+	}
+}
@@ -0,0 +1,149 @@
+/*
+Package openapi generates OpenAPI 3 response objects, with examples, from
+responses captured by httpspy spies.
+
+Schemas are inferred from a single example body and are best-effort.  They
+describe the types found in that body but cannot express optional fields,
+enumerations, or formats.
+
+Like httpspy, the openapi API is experimental and may change without notice.
+*/
+package openapi
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bmatsuo/httpspy"
+)
+
+// A Schema is a minimal OpenAPI 3 schema object.
+type Schema struct {
+	Type       string             `json:"type,omitempty"`
+	Format     string             `json:"format,omitempty"`
+	Nullable   bool               `json:"nullable,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+}
+
+// A MediaType is an OpenAPI 3 media type object.
+type MediaType struct {
+	Schema  *Schema     `json:"schema,omitempty"`
+	Example interface{} `json:"example,omitempty"`
+}
+
+// A Response is an OpenAPI 3 response object.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// A Collection retains one captured response per path, method and status code.
+// A Collection is safe for concurrent use.
+type Collection struct {
+	ex *httpspy.Examples
+}
+
+// NewCollection returns a Collection retaining at most maxBody bytes of each
+// response body.  Bodies larger than maxBody produce a media type with neither
+// a schema nor an example, as a prefix of the body cannot describe it.
+func NewCollection(maxBody int) *Collection {
+	return &Collection{ex: httpspy.NewExamples(maxBody, false)}
+}
+
+// Add records the response observed by s for a request with the given method
+// and path template, such as "/pets/{id}".  The first response for each status
+// code is kept.
+func (c *Collection) Add(method, path string, s httpspy.WriteSpy) {
+	c.ex.Add(strings.ToLower(method)+" "+path, s)
+}
+
+// Responses returns the OpenAPI responses for the collected examples indexed
+// by path, lowercase method, and status code, matching the layout of the
+// OpenAPI paths object.
+func (c *Collection) Responses() map[string]map[string]map[string]Response {
+	paths := make(map[string]map[string]map[string]Response)
+	for route, codes := range c.ex.Map() {
+		i := strings.Index(route, " ")
+		method, path := route[:i], route[i+1:]
+		if paths[path] == nil {
+			paths[path] = make(map[string]map[string]Response)
+		}
+		responses := make(map[string]Response, len(codes))
+		for code, ex := range codes {
+			responses[strconv.Itoa(code)] = NewResponse(ex)
+		}
+		paths[path][method] = responses
+	}
+	return paths
+}
+
+// NewResponse returns the OpenAPI response object describing ex.
+func NewResponse(ex httpspy.Example) Response {
+	resp := Response{Description: http.StatusText(ex.Code)}
+	if len(ex.Body) == 0 {
+		return resp
+	}
+
+	ct := ex.Header.Get("Content-Type")
+	if ct == "" {
+		ct = http.DetectContentType(ex.Body)
+	}
+	typ, _, err := mime.ParseMediaType(ct)
+	if err != nil {
+		typ = "application/octet-stream"
+	}
+
+	var m MediaType
+	var v interface{}
+	switch {
+	case ex.Truncated:
+		// Only the media type is known.
+	case isJSON(typ) && json.Unmarshal(ex.Body, &v) == nil:
+		m.Schema = InferSchema(v)
+		m.Example = v
+	case strings.HasPrefix(typ, "text/"):
+		m.Schema = &Schema{Type: "string"}
+		m.Example = string(ex.Body)
+	default:
+		m.Schema = &Schema{Type: "string", Format: "binary"}
+	}
+	resp.Content = map[string]MediaType{typ: m}
+	return resp
+}
+
+// InferSchema returns a schema describing the decoded JSON value v.  The items
+// schema of an array is inferred from its first element.
+func InferSchema(v interface{}) *Schema {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema, len(v))}
+		for k, elem := range v {
+			s.Properties[k] = InferSchema(elem)
+		}
+		return s
+	case []interface{}:
+		s := &Schema{Type: "array", Items: &Schema{}}
+		if len(v) > 0 {
+			s.Items = InferSchema(v[0])
+		}
+		return s
+	case string:
+		return &Schema{Type: "string"}
+	case float64:
+		if v == float64(int64(v)) {
+			return &Schema{Type: "integer"}
+		}
+		return &Schema{Type: "number"}
+	case bool:
+		return &Schema{Type: "boolean"}
+	}
+	return &Schema{Nullable: true}
+}
+
+func isJSON(typ string) bool {
+	return typ == "application/json" || strings.HasSuffix(typ, "+json")
+}
@@ -0,0 +1,58 @@
+package openapi
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bmatsuo/httpspy"
+)
+
+func TestCollection(t *testing.T) {
+	c := NewCollection(1 << 10)
+
+	s := httpspy.NewWriteSpy(httptest.NewRecorder())
+	s.Header().Set("Content-Type", "application/json; charset=utf-8")
+	io.WriteString(s, `{"id":1,"name":"bowser","tags":["good"],"weight":9.5,"owner":null}`)
+	c.Add("GET", "/pets/{id}", s)
+
+	s = httpspy.NewWriteSpy(httptest.NewRecorder())
+	s.Header().Set("Content-Type", "text/plain")
+	s.WriteHeader(404)
+	io.WriteString(s, "not found")
+	c.Add("GET", "/pets/{id}", s)
+
+	p, err := json.Marshal(c.Responses())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"/pets/{id}":{"get":{` +
+		`"200":{"description":"OK","content":{"application/json":{` +
+		`"schema":{"type":"object","properties":{` +
+		`"id":{"type":"integer"},"name":{"type":"string"},"owner":{"nullable":true},` +
+		`"tags":{"type":"array","items":{"type":"string"}},"weight":{"type":"number"}}},` +
+		`"example":{"id":1,"name":"bowser","owner":null,"tags":["good"],"weight":9.5}}}},` +
+		`"404":{"description":"Not Found","content":{"text/plain":{` +
+		`"schema":{"type":"string"},"example":"not found"}}}}}}`
+	if string(p) != want {
+		t.Errorf("responses:\n%s\nwant:\n%s", p, want)
+	}
+}
+
+func TestNewResponseTruncated(t *testing.T) {
+	c := NewCollection(4)
+	s := httpspy.NewWriteSpy(httptest.NewRecorder())
+	s.Header().Set("Content-Type", "application/json")
+	io.WriteString(s, `{"id":1}`)
+	c.Add("GET", "/pets/{id}", s)
+
+	p, err := json.Marshal(c.Responses())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"/pets/{id}":{"get":{"200":{"description":"OK","content":{"application/json":{}}}}}}`
+	if string(p) != want {
+		t.Errorf("responses:\n%s\nwant:\n%s", p, want)
+	}
+}
@@ -0,0 +1,85 @@
+package httpspy
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// A Replayer serves stored captures, such as those held by a CaptureStore,
+// optionally simulating a slow backend.  The zero value replays immediately
+// at full speed.
+type Replayer struct {
+	// Delay is the time to wait before writing the response header.
+	Delay time.Duration
+	// BytesPerSec caps the rate the body is written at.  Zero means no cap.
+	BytesPerSec int64
+}
+
+// replayInterval is the period a throttled Replayer writes a chunk of body at.
+const replayInterval = 100 * time.Millisecond
+
+// Replay writes ex to w.  When throttled the body is written in chunks,
+// flushing after each if w is an http.Flusher, and Replay does not return
+// before the body could have been sent at r.BytesPerSec.
+// Replay returns early with ctx.Err() if ctx is done while waiting, and
+// returns any error writing the body.  The Content-Length header is removed
+// from truncated captures.
+func (r Replayer) Replay(ctx context.Context, w http.ResponseWriter, ex Example) error {
+	if err := sleepContext(ctx, r.Delay); err != nil {
+		return err
+	}
+
+	h := w.Header()
+	for k, v := range ex.Header {
+		h[k] = append([]string(nil), v...)
+	}
+	if ex.Truncated {
+		h.Del("Content-Length")
+	}
+	if ex.Code != 0 {
+		w.WriteHeader(ex.Code)
+	}
+
+	body := ex.Body
+	if r.BytesPerSec <= 0 {
+		_, err := w.Write(body)
+		return err
+	}
+	chunk := int(r.BytesPerSec * int64(replayInterval) / int64(time.Second))
+	if chunk < 1 {
+		chunk = 1
+	}
+	flusher, _ := w.(http.Flusher)
+	for len(body) > 0 {
+		n := chunk
+		if n > len(body) {
+			n = len(body)
+		}
+		if _, err := w.Write(body[:n]); err != nil {
+			return err
+		}
+		body = body[n:]
+		if flusher != nil {
+			flusher.Flush()
+		}
+		if err := sleepContext(ctx, time.Duration(int64(n)*int64(time.Second)/r.BytesPerSec)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,39 @@
+package httpspy
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplayer(t *testing.T) {
+	ex := Example{
+		Code:   201,
+		Header: map[string][]string{"Content-Type": {"text/plain"}},
+		Body:   []byte(strings.Repeat("x", 100)),
+	}
+	r := Replayer{Delay: 10 * time.Millisecond, BytesPerSec: 1000}
+	rec := httptest.NewRecorder()
+	start := time.Now()
+	if err := r.Replay(context.Background(), rec, ex); err != nil {
+		t.Fatal(err)
+	}
+	// 100 bytes at 1000 bytes per second take 100ms after the delay.
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Errorf("replay took %v", d)
+	}
+	if rec.Code != 201 || rec.Body.String() != string(ex.Body) || rec.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("code=%d header=%v body=%q", rec.Code, rec.Header(), rec.Body)
+	}
+	if !rec.Flushed {
+		t.Errorf("throttled replay not flushed")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := r.Replay(ctx, httptest.NewRecorder(), ex); err != context.Canceled {
+		t.Errorf("canceled replay: %v", err)
+	}
+}
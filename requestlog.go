@@ -0,0 +1,89 @@
+package httpspy
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// DefaultRequestIDHeader is the header used by a LogHandler with no Header.
+const DefaultRequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+// RequestID returns the request id stored in ctx by a LogHandler, or the
+// empty string.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// A LogHandler serves requests with Handler and emits a structured log line
+// for each response.  Every request is given an id, taken from the request
+// header or generated, which is set on the response header before the handler
+// runs and made available to it through RequestID.
+type LogHandler struct {
+	Handler http.Handler
+	// Logger receives log lines.  If nil slog.Default() is used.
+	Logger *slog.Logger
+	// Header names the request id header.  If empty DefaultRequestIDHeader is
+	// used.
+	Header string
+	// NewID generates ids for requests without one.  If nil random 128-bit
+	// hex ids are generated.
+	NewID func() string
+	// Sample, if not nil, is called after the handler returns and the
+	// response is only logged if it returns true.
+	Sample func(req *http.Request, s Spy) bool
+}
+
+func (h *LogHandler) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	header := h.Header
+	if header == "" {
+		header = DefaultRequestIDHeader
+	}
+	id := req.Header.Get(header)
+	if id == "" {
+		if h.NewID != nil {
+			id = h.NewID()
+		} else {
+			id = randomID()
+		}
+	}
+	resp.Header().Set(header, id)
+	req = req.WithContext(context.WithValue(req.Context(), requestIDKey{}, id))
+
+	timing := NewTimingSpy(resp, start)
+	h.Handler.ServeHTTP(timing, req)
+
+	if h.Sample != nil && !h.Sample(req, timing) {
+		return
+	}
+	logger := h.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	code := timing.Code()
+	if code == 0 {
+		code = http.StatusOK
+	}
+	logger.LogAttrs(req.Context(), slog.LevelInfo, "request",
+		slog.String("request_id", id),
+		slog.String("method", req.Method),
+		slog.String("path", req.URL.Path),
+		slog.Int("status", code),
+		slog.Int64("bytes", timing.BytesWritten()),
+		slog.Duration("ttfb", timing.Timing().TTFB),
+		slog.Duration("duration", time.Since(start)),
+	)
+}
+
+func randomID() string {
+	var p [16]byte
+	rand.Read(p[:])
+	return hex.EncodeToString(p[:])
+}
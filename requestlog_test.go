@@ -0,0 +1,60 @@
+package httpspy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := &LogHandler{
+		Handler: http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			resp.WriteHeader(http.StatusCreated)
+			io.WriteString(resp, RequestID(req.Context()))
+		}),
+		Logger: slog.New(slog.NewJSONHandler(&buf, nil)),
+		NewID:  func() string { return "generated" },
+		Sample: func(req *http.Request, s Spy) bool { return req.URL.Path != "/skip" },
+	}
+
+	for _, test := range []struct {
+		path, id, want string
+	}{
+		{"/", "", "generated"},
+		{"/", "abc", "abc"},
+	} {
+		buf.Reset()
+		req := httptest.NewRequest("GET", test.path, nil)
+		if test.id != "" {
+			req.Header.Set("X-Request-Id", test.id)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Header().Get("X-Request-Id") != test.want || rec.Body.String() != test.want {
+			t.Errorf("id %q: header %q body %q", test.id, rec.Header().Get("X-Request-Id"), rec.Body)
+		}
+		var line struct {
+			RequestID string `json:"request_id"`
+			Status    int    `json:"status"`
+			Bytes     int64  `json:"bytes"`
+		}
+		if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+			t.Fatal(err)
+		}
+		if line.RequestID != test.want || line.Status != 201 || line.Bytes != int64(len(test.want)) {
+			t.Errorf("id %q: log %s", test.id, buf.Bytes())
+		}
+	}
+
+	buf.Reset()
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/skip", nil))
+	if buf.Len() != 0 {
+		t.Errorf("unsampled request logged: %s", buf.Bytes())
+	}
+}
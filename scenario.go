@@ -0,0 +1,31 @@
+package httpspy
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// A ScenarioEncoder writes captured interactions in the scenario format of a
+// load testing tool.  Encoders for particular tools live outside httpspy.
+type ScenarioEncoder interface {
+	Encode(rec TraceRecord) error
+}
+
+// ExportScenario reads a trace written by a TraceWriter from r and encodes
+// each record with enc.
+func ExportScenario(r io.Reader, enc ScenarioEncoder) error {
+	dec := json.NewDecoder(r)
+	for {
+		var rec TraceRecord
+		err := dec.Decode(&rec)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := enc.Encode(rec); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package spytest
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+
+	"github.com/bmatsuo/httpspy"
+)
+
+// A DiffOption configures the comparison made by DiffHandlers.
+type DiffOption func(*diffConfig)
+
+type diffConfig struct {
+	ignore map[string]bool
+}
+
+// IgnoreHeaders causes DiffHandlers to skip the named response headers, such
+// as Date, which are expected to differ between handlers.
+func IgnoreHeaders(names ...string) DiffOption {
+	return func(c *diffConfig) {
+		for _, name := range names {
+			c.ignore[http.CanonicalHeaderKey(name)] = true
+		}
+	}
+}
+
+// DiffHandlers serves req with both a and b and reports any difference in
+// the status code, headers, or body of their responses as an error on tb.
+// The request body is buffered so each handler reads it in full.
+// DiffHandlers returns true if the responses are equal.
+func DiffHandlers(tb testing.TB, a, b http.Handler, req *http.Request, opts ...DiffOption) bool {
+	tb.Helper()
+	c := &diffConfig{ignore: make(map[string]bool)}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	sa, sb, err := serveBoth(a, b, req)
+	if err != nil {
+		tb.Errorf("reading request body: %v", err)
+		return false
+	}
+
+	equal := true
+	if sa.Code() != sb.Code() {
+		tb.Errorf("status code: %d != %d", sa.Code(), sb.Code())
+		equal = false
+	}
+	for _, key := range headerKeys(sa.Header(), sb.Header()) {
+		if c.ignore[key] {
+			continue
+		}
+		va, vb := sa.Header()[key], sb.Header()[key]
+		if !stringsEqual(va, vb) {
+			tb.Errorf("header %s: %q != %q", key, va, vb)
+			equal = false
+		}
+	}
+	if !bytes.Equal(sa.Body(), sb.Body()) {
+		tb.Errorf("body: %q != %q", sa.Body(), sb.Body())
+		equal = false
+	}
+	return equal
+}
+
+// serveBoth serves req with a and b, recording their responses.  The request
+// body is buffered so each handler reads it in full.
+func serveBoth(a, b http.Handler, req *http.Request) (sa, sb httpspy.WriteSpy, err error) {
+	var body []byte
+	if req.Body != nil {
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	serve := func(h http.Handler) httpspy.WriteSpy {
+		r := req.Clone(req.Context())
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		s := httpspy.NewWriteSpy(httptest.NewRecorder())
+		h.ServeHTTP(s, r)
+		return s
+	}
+	return serve(a), serve(b), nil
+}
+
+// headerKeys returns the sorted union of keys in h1 and h2.
+func headerKeys(h1, h2 http.Header) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, h := range []http.Header{h1, h2} {
+		for k := range h {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package spytest
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// recordTB captures errors reported by the helpers under test.
+type recordTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *recordTB) Helper() {}
+
+func (tb *recordTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func echo(prefix string) http.Handler {
+	return http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		resp.Header().Set("X-Prefix", prefix)
+		resp.Header().Set("Content-Type", "text/plain")
+		io.WriteString(resp, "echo: ")
+		io.Copy(resp, req.Body)
+	})
+}
+
+func TestDiffHandlers(t *testing.T) {
+	req := httptest.NewRequest("POST", "/", strings.NewReader("hi"))
+	if !DiffHandlers(t, echo("a"), echo("b"), req, IgnoreHeaders("x-prefix")) {
+		t.Errorf("handlers differ")
+	}
+
+	tb := &recordTB{TB: t}
+	req = httptest.NewRequest("POST", "/", strings.NewReader("hi"))
+	if DiffHandlers(tb, echo("a"), echo("b"), req) {
+		t.Errorf("handlers equal")
+	}
+	if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], "X-Prefix") {
+		t.Errorf("errors: %q", tb.errors)
+	}
+}
@@ -0,0 +1,45 @@
+package spytest
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/bmatsuo/httpspy"
+)
+
+// AssertJSONFields decodes the body captured by s as a JSON object and reports
+// an error on tb for each field that is missing or null.  Fields may be dotted
+// paths, such as "user.name", to name fields of nested objects.
+// AssertJSONFields returns true if all fields are present.
+func AssertJSONFields(tb testing.TB, s httpspy.WriteSpy, fields ...string) bool {
+	tb.Helper()
+	var obj map[string]interface{}
+	if err := json.Unmarshal(s.Body(), &obj); err != nil {
+		tb.Errorf("decoding JSON body: %v", err)
+		return false
+	}
+
+	ok := true
+	for _, field := range fields {
+		if lookupJSON(obj, field) == nil {
+			tb.Errorf("JSON field %q is missing or null", field)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// lookupJSON returns the value at the dotted path in obj or nil if any
+// element of the path is missing.
+func lookupJSON(obj map[string]interface{}, path string) interface{} {
+	var v interface{} = obj
+	for _, key := range strings.Split(path, ".") {
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		v = m[key]
+	}
+	return v
+}
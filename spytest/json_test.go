@@ -0,0 +1,26 @@
+package spytest
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bmatsuo/httpspy"
+)
+
+func TestAssertJSONFields(t *testing.T) {
+	s := httpspy.NewWriteSpy(httptest.NewRecorder())
+	io.WriteString(s, `{"id":1,"user":{"name":"bowser","email":null},"tags":[]}`)
+
+	if !AssertJSONFields(t, s, "id", "user.name", "tags") {
+		t.Errorf("expected fields missing")
+	}
+
+	tb := &recordTB{TB: t}
+	if AssertJSONFields(tb, s, "user.email", "user.name.first", "missing") {
+		t.Errorf("missing fields found")
+	}
+	if len(tb.errors) != 3 {
+		t.Errorf("errors: %q", tb.errors)
+	}
+}
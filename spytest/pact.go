@@ -0,0 +1,139 @@
+package spytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/bmatsuo/httpspy"
+)
+
+// A Pact describes the response a consumer expects from a provider.
+type Pact struct {
+	// Status is the expected status code.  Zero matches any status.
+	Status int
+	// Headers maps required header names to their expected values.  An empty
+	// value only requires the header to be present.  A header key holding no
+	// values, such as a suppressed Date, counts as missing.
+	Headers map[string]string
+	// Body holds matchers for the JSON response body.
+	Body []Matcher
+}
+
+// A MatchKind selects how a Matcher compares a JSON value.
+type MatchKind int
+
+// The kinds of body matchers.
+const (
+	// MatchExact requires the value to equal Matcher.Value.
+	MatchExact MatchKind = iota
+	// MatchType requires the value to have the same JSON type as
+	// Matcher.Value.
+	MatchType
+	// MatchRegex requires the value to be a string matching the regular
+	// expression Matcher.Value.
+	MatchRegex
+)
+
+// A Matcher checks one value in a JSON body.
+type Matcher struct {
+	// Path is the dotted path of the value, as in AssertJSONFields.  The
+	// empty path names the whole body.
+	Path  string
+	Kind  MatchKind
+	Value interface{}
+}
+
+// VerifyPact reports an error on tb for each way the response observed by s
+// fails to satisfy pact.  VerifyPact returns true if the response satisfies
+// the pact.
+func VerifyPact(tb testing.TB, s httpspy.WriteSpy, pact Pact) bool {
+	tb.Helper()
+	ok := true
+	fail := func(format string, args ...interface{}) {
+		tb.Errorf("pact: "+format, args...)
+		ok = false
+	}
+
+	if pact.Status != 0 && s.Code() != pact.Status {
+		fail("status %d, expected %d", s.Code(), pact.Status)
+	}
+	for name, want := range pact.Headers {
+		got := s.Header()[http.CanonicalHeaderKey(name)]
+		switch {
+		case len(got) == 0:
+			fail("header %s missing", name)
+		case want != "" && got[0] != want:
+			fail("header %s is %q, expected %q", name, got[0], want)
+		}
+	}
+	if len(pact.Body) == 0 {
+		return ok
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(s.Body(), &body); err != nil {
+		fail("decoding JSON body: %v", err)
+		return false
+	}
+	for _, m := range pact.Body {
+		if err := m.match(body); err != nil {
+			fail("body %q: %v", m.Path, err)
+		}
+	}
+	return ok
+}
+
+func (m Matcher) match(body interface{}) error {
+	v := body
+	if m.Path != "" {
+		obj, _ := body.(map[string]interface{})
+		v = lookupJSON(obj, m.Path)
+	}
+
+	switch m.Kind {
+	case MatchExact:
+		want, err := normalizeJSON(m.Value)
+		if err != nil {
+			return err
+		}
+		if !reflect.DeepEqual(v, want) {
+			return fmt.Errorf("%v, expected %v", v, want)
+		}
+	case MatchType:
+		want, err := normalizeJSON(m.Value)
+		if err != nil {
+			return err
+		}
+		if jsonType(v) != jsonType(want) {
+			return fmt.Errorf("type %s, expected %s", jsonType(v), jsonType(want))
+		}
+	case MatchRegex:
+		pattern, _ := m.Value.(string)
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		str, isString := v.(string)
+		if !isString || !re.MatchString(str) {
+			return fmt.Errorf("%v does not match %q", v, pattern)
+		}
+	default:
+		return fmt.Errorf("unknown match kind %d", m.Kind)
+	}
+	return nil
+}
+
+// normalizeJSON converts v to the types encoding/json decodes into.
+func normalizeJSON(v interface{}) (interface{}, error) {
+	p, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var norm interface{}
+	err = json.Unmarshal(p, &norm)
+	return norm, err
+}
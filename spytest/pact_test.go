@@ -0,0 +1,59 @@
+package spytest
+
+import (
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bmatsuo/httpspy"
+)
+
+func TestVerifyPact(t *testing.T) {
+	s := httpspy.NewWriteSpy(httptest.NewRecorder())
+	s.Header().Set("Content-Type", "application/json")
+	s.Header().Set("Location", "/pets/1")
+	s.WriteHeader(201)
+	io.WriteString(s, `{"id":1,"name":"bowser","owner":{"email":"a@example.com"}}`)
+
+	pact := Pact{
+		Status:  201,
+		Headers: map[string]string{"content-type": "application/json", "Location": ""},
+		Body: []Matcher{
+			{Path: "id", Kind: MatchExact, Value: 1},
+			{Path: "name", Kind: MatchType, Value: "string"},
+			{Path: "owner.email", Kind: MatchRegex, Value: `^\S+@\S+$`},
+			{Kind: MatchType, Value: map[string]interface{}{}},
+		},
+	}
+	if !VerifyPact(t, s, pact) {
+		t.Errorf("pact not satisfied")
+	}
+
+	pact = Pact{
+		Status:  200,
+		Headers: map[string]string{"Location": "/pets/2", "ETag": ""},
+		Body: []Matcher{
+			{Path: "id", Kind: MatchExact, Value: 2},
+			{Path: "id", Kind: MatchType, Value: "1"},
+			{Path: "name", Kind: MatchRegex, Value: `^meow`},
+			{Path: "missing", Kind: MatchType, Value: 0},
+		},
+	}
+	tb := &recordTB{TB: t}
+	if VerifyPact(tb, s, pact) {
+		t.Errorf("pact satisfied")
+	}
+	if len(tb.errors) != 7 {
+		t.Errorf("errors: %q", tb.errors)
+	}
+
+	// A suppressed header is present in the map without values.
+	s.Header()["Date"] = nil
+	tb = &recordTB{TB: t}
+	if VerifyPact(tb, s, Pact{Headers: map[string]string{"Date": "today"}}) {
+		t.Errorf("pact with suppressed header satisfied")
+	}
+	if len(tb.errors) != 1 {
+		t.Errorf("errors: %q", tb.errors)
+	}
+}
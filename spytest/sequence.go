@@ -0,0 +1,51 @@
+package spytest
+
+import (
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/bmatsuo/httpspy"
+)
+
+// AssertSequence reports an error on tb unless the events recorded by s match
+// steps in order.  Each step is a pattern matched against the String form of
+// one event, such as "Header()", "WriteHeader(200)" or "Write(12)".  Patterns
+// use the syntax of path.Match, so "Write(*)" matches a write of any size and
+// "*" matches any event.  The special step "..." matches any number of
+// events, including none.  AssertSequence returns true if the events match.
+func AssertSequence(tb testing.TB, s httpspy.EventSpy, steps ...string) bool {
+	tb.Helper()
+	events := s.Events()
+	actual := make([]string, len(events))
+	for i, e := range events {
+		actual[i] = e.String()
+	}
+	if matchSequence(steps, actual) {
+		return true
+	}
+	tb.Errorf("event sequence mismatch\nexpected: %s\nactual:   %s",
+		strings.Join(steps, " -> "), strings.Join(actual, " -> "))
+	return false
+}
+
+func matchSequence(steps, events []string) bool {
+	for len(steps) > 0 {
+		if steps[0] == "..." {
+			for i := len(events); i >= 0; i-- {
+				if matchSequence(steps[1:], events[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(events) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(steps[0], events[0]); !ok {
+			return false
+		}
+		steps, events = steps[1:], events[1:]
+	}
+	return len(events) == 0
+}
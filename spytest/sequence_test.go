@@ -0,0 +1,45 @@
+package spytest
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bmatsuo/httpspy"
+)
+
+func TestAssertSequence(t *testing.T) {
+	s := httpspy.NewEventSpy(httptest.NewRecorder())
+	s.Header().Set("Content-Type", "text/plain")
+	s.WriteHeader(200)
+	io.WriteString(s, "hello")
+	io.WriteString(s, ", world")
+
+	for _, steps := range [][]string{
+		{"Header()", "WriteHeader(200)", "Write(5)", "Write(7)"},
+		{"Header()", "WriteHeader(2*)", "Write(*)", "*"},
+		{"...", "Write(7)"},
+		{"Header()", "..."},
+		{"Header()", "...", "WriteHeader(200)", "...", "Write(*)", "..."},
+	} {
+		if !AssertSequence(t, s, steps...) {
+			t.Errorf("steps %q did not match", steps)
+		}
+	}
+
+	for _, steps := range [][]string{
+		{"Header()", "WriteHeader(200)", "Write(5)"},
+		{"WriteHeader(200)", "..."},
+		{"...", "Header()"},
+		{},
+	} {
+		tb := &recordTB{TB: t}
+		if AssertSequence(tb, s, steps...) {
+			t.Errorf("steps %q matched", steps)
+		}
+		if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], "actual:   Header() -> WriteHeader(200) -> Write(5) -> Write(7)") {
+			t.Errorf("errors: %q", tb.errors)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package spytest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+)
+
+// JSONShape returns the structure of the JSON document data, ignoring values.
+// The result maps each path in the document to the type found there: one of
+// "object", "array", "string", "number", "boolean" or "null".  The root has
+// the path ".", object fields are named by dotted paths such as "user.name"
+// and array elements share the path of their array with "[]" appended, so
+// "items[].id" is the id field of every element of items.  If elements of an
+// array have different types at a path the type is "mixed".
+func JSONShape(data []byte) (map[string]string, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	shape := make(map[string]string)
+	addShape(shape, "", v)
+	return shape, nil
+}
+
+func addShape(shape map[string]string, path string, v interface{}) {
+	typ := jsonType(v)
+	key := path
+	if key == "" {
+		key = "."
+	}
+	if old, ok := shape[key]; ok && old != typ {
+		typ = "mixed"
+	}
+	shape[key] = typ
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, elem := range v {
+			if path == "" {
+				addShape(shape, k, elem)
+			} else {
+				addShape(shape, path+"."+k, elem)
+			}
+		}
+	case []interface{}:
+		for _, elem := range v {
+			addShape(shape, path+"[]", elem)
+		}
+	}
+}
+
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	}
+	return "null"
+}
+
+// A ShapeDiff lists the differences between two JSON shapes.  Each list is
+// sorted.
+type ShapeDiff struct {
+	// Added holds paths only present in the second shape.
+	Added []string
+	// Removed holds paths only present in the first shape.
+	Removed []string
+	// Changed describes paths whose type differs, as "path: old -> new".
+	Changed []string
+}
+
+// Empty returns true if d contains no differences.
+func (d ShapeDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffShapes compares the JSON shapes a and b.
+func DiffShapes(a, b map[string]string) ShapeDiff {
+	var d ShapeDiff
+	for path, typA := range a {
+		typB, ok := b[path]
+		switch {
+		case !ok:
+			d.Removed = append(d.Removed, path)
+		case typA != typB:
+			d.Changed = append(d.Changed, fmt.Sprintf("%s: %s -> %s", path, typA, typB))
+		}
+	}
+	for path := range b {
+		if _, ok := a[path]; !ok {
+			d.Added = append(d.Added, path)
+		}
+	}
+	sort.Strings(d.Added)
+	sort.Strings(d.Removed)
+	sort.Strings(d.Changed)
+	return d
+}
+
+// DiffJSONShapes serves req with the handlers v1 and v2 and returns the
+// difference between the shapes of their JSON response bodies.  An error is
+// reported on tb if either body is not JSON.
+func DiffJSONShapes(tb testing.TB, v1, v2 http.Handler, req *http.Request) ShapeDiff {
+	tb.Helper()
+	s1, s2, err := serveBoth(v1, v2, req)
+	if err != nil {
+		tb.Errorf("reading request body: %v", err)
+		return ShapeDiff{}
+	}
+	shape1, err := JSONShape(s1.Body())
+	if err != nil {
+		tb.Errorf("decoding first JSON body: %v", err)
+		return ShapeDiff{}
+	}
+	shape2, err := JSONShape(s2.Body())
+	if err != nil {
+		tb.Errorf("decoding second JSON body: %v", err)
+		return ShapeDiff{}
+	}
+	return DiffShapes(shape1, shape2)
+}
@@ -0,0 +1,77 @@
+package spytest
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestJSONShape(t *testing.T) {
+	for _, test := range []struct {
+		json  string
+		shape map[string]string
+	}{
+		{`null`, map[string]string{".": "null"}},
+		{`[]`, map[string]string{".": "array"}},
+		{`[1, 2]`, map[string]string{".": "array", "[]": "number"}},
+		{`[1, "two"]`, map[string]string{".": "array", "[]": "mixed"}},
+		{
+			`{"id": 1, "ok": true, "user": {"name": "x", "tags": ["a"]}}`,
+			map[string]string{
+				".":           "object",
+				"id":          "number",
+				"ok":          "boolean",
+				"user":        "object",
+				"user.name":   "string",
+				"user.tags":   "array",
+				"user.tags[]": "string",
+			},
+		},
+		{
+			`{"items": [{"id": 1}, {"id": 2, "extra": null}]}`,
+			map[string]string{
+				".":             "object",
+				"items":         "array",
+				"items[]":       "object",
+				"items[].id":    "number",
+				"items[].extra": "null",
+			},
+		},
+	} {
+		shape, err := JSONShape([]byte(test.json))
+		if err != nil {
+			t.Errorf("%s: %v", test.json, err)
+			continue
+		}
+		if !reflect.DeepEqual(shape, test.shape) {
+			t.Errorf("%s: %v", test.json, shape)
+		}
+	}
+
+	if _, err := JSONShape([]byte(`{`)); err == nil {
+		t.Errorf("expected error for malformed JSON")
+	}
+}
+
+func TestDiffJSONShapes(t *testing.T) {
+	v1 := http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		io.WriteString(resp, `{"id": 1, "name": "bowser", "age": 3}`)
+	})
+	v2 := http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		io.WriteString(resp, `{"id": "1", "name": "bowser", "owner": {"id": 2}}`)
+	})
+	d := DiffJSONShapes(t, v1, v2, httptest.NewRequest("GET", "/", nil))
+	want := ShapeDiff{
+		Added:   []string{"owner", "owner.id"},
+		Removed: []string{"age"},
+		Changed: []string{"id: number -> string"},
+	}
+	if !reflect.DeepEqual(d, want) {
+		t.Errorf("diff: %+v", d)
+	}
+	if d.Empty() || !(ShapeDiff{}).Empty() {
+		t.Errorf("Empty")
+	}
+}
@@ -0,0 +1,6 @@
+/*
+Package spytest provides test helpers built on httpspy.
+
+Like httpspy, the spytest API is experimental and may change without notice.
+*/
+package spytest
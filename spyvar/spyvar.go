@@ -0,0 +1,60 @@
+/*
+Package spyvar publishes response statistics gathered by httpspy spies as
+expvar variables, visible at /debug/vars.
+
+Like httpspy, the spyvar API is experimental and may change without notice.
+*/
+package spyvar
+
+import (
+	"expvar"
+	"net/http"
+	"strconv"
+
+	"github.com/bmatsuo/httpspy"
+)
+
+// Counters holds published counts of responses by status class and of the
+// body bytes written.  Counters is safe for concurrent use.
+type Counters struct {
+	Requests *expvar.Map
+	Bytes    *expvar.Int
+}
+
+// New returns unpublished Counters.  The Requests map holds counts by status
+// class ("2xx", "4xx", ...).
+func New() *Counters {
+	return &Counters{
+		Requests: new(expvar.Map),
+		Bytes:    new(expvar.Int),
+	}
+}
+
+// Publish registers c.Requests and c.Bytes under the expvar names requests and
+// bytes.  Like expvar.Publish, Publish panics if either name is already
+// registered, so it should be called once, typically from an init function.
+func (c *Counters) Publish(requests, bytes string) {
+	expvar.Publish(requests, c.Requests)
+	expvar.Publish(bytes, c.Bytes)
+}
+
+// Observe counts the response observed by s.  It should only be called after
+// the handler has returned.  A spy reporting a zero code is counted as 2xx
+// because net/http responds 200 to handlers that write nothing.  Body bytes
+// are counted when s has a BytesWritten method or is an httpspy.WriteSpy.
+func (c *Counters) Observe(s httpspy.Spy) {
+	code := s.Code()
+	if code == 0 {
+		code = http.StatusOK
+	}
+	c.Requests.Add(strconv.Itoa(code/100)+"xx", 1)
+
+	switch s := s.(type) {
+	case interface {
+		BytesWritten() int64
+	}:
+		c.Bytes.Add(s.BytesWritten())
+	case httpspy.WriteSpy:
+		c.Bytes.Add(int64(len(s.Body())))
+	}
+}
@@ -0,0 +1,33 @@
+package spyvar
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bmatsuo/httpspy"
+)
+
+func TestCounters(t *testing.T) {
+	c := New()
+
+	s := httpspy.NewWriteSpy(httptest.NewRecorder())
+	s.Write([]byte("hello"))
+	c.Observe(s)
+
+	ls := httpspy.NewLabelSpy(httptest.NewRecorder())
+	ls.WriteHeader(404)
+	ls.Write([]byte("not found"))
+	c.Observe(ls)
+
+	c.Observe(httpspy.NewSpy(httptest.NewRecorder()))
+
+	if v := c.Requests.Get("2xx").String(); v != "2" {
+		t.Errorf("2xx = %s", v)
+	}
+	if v := c.Requests.Get("4xx").String(); v != "1" {
+		t.Errorf("4xx = %s", v)
+	}
+	if v := c.Bytes.Value(); v != 14 {
+		t.Errorf("bytes = %d", v)
+	}
+}
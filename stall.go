@@ -0,0 +1,69 @@
+package httpspy
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A StallSpy is a Spy that counts writes that blocked for a long time in the
+// underlying http.ResponseWriter.
+//
+// net/http exposes no frame-level details of HTTP/2 streams to handlers, so
+// flow-control stalls can only be inferred.  A write blocks when the server's
+// send buffer is full, which on HTTP/2 usually means the stream or connection
+// flow-control window is exhausted.  Slow clients and congested networks
+// produce the same signal, so the count is a heuristic.
+type StallSpy interface {
+	Spy
+	// SuspectedFlowControlStalls returns the number of calls to Write that
+	// blocked for longer than the spy's threshold.
+	SuspectedFlowControlStalls() int
+	// StallTime returns the total time spent in writes counted as stalls.
+	StallTime() time.Duration
+}
+
+// NewStallSpy returns a generic, threadsafe StallSpy counting writes that
+// take longer than threshold.
+func NewStallSpy(w http.ResponseWriter, threshold time.Duration) StallSpy {
+	s := new(stallSpy)
+	s.simpleSpy = new(simpleSpy)
+	s.simpleSpy.w = w
+	s.threshold = threshold
+	return s
+}
+
+type stallSpy struct {
+	*simpleSpy
+	threshold time.Duration
+	mut       sync.Mutex
+	stalls    int
+	stallTime time.Duration
+}
+
+func (s *stallSpy) Write(p []byte) (int, error) {
+	start := time.Now()
+	n, err := s.simpleSpy.Write(p)
+	d := time.Since(start)
+	if d > s.threshold {
+		s.mut.Lock()
+		s.stalls++
+		s.stallTime += d
+		s.mut.Unlock()
+	}
+	return n, err
+}
+
+func (s *stallSpy) SuspectedFlowControlStalls() int {
+	s.mut.Lock()
+	n := s.stalls
+	s.mut.Unlock()
+	return n
+}
+
+func (s *stallSpy) StallTime() time.Duration {
+	s.mut.Lock()
+	d := s.stallTime
+	s.mut.Unlock()
+	return d
+}
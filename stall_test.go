@@ -0,0 +1,34 @@
+package httpspy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// slowWriter blocks in Write for writes longer than one byte.
+type slowWriter struct {
+	http.ResponseWriter
+}
+
+func (w slowWriter) Write(p []byte) (int, error) {
+	if len(p) > 1 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func TestStallSpy(t *testing.T) {
+	s := NewStallSpy(slowWriter{httptest.NewRecorder()}, time.Millisecond)
+	s.Write([]byte("a"))
+	s.Write([]byte("slow"))
+	s.Write([]byte("b"))
+	s.Write([]byte("slow"))
+	if n := s.SuspectedFlowControlStalls(); n != 2 {
+		t.Errorf("%d stalls", n)
+	}
+	if d := s.StallTime(); d < 10*time.Millisecond {
+		t.Errorf("stall time %v", d)
+	}
+}
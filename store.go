@@ -0,0 +1,120 @@
+package httpspy
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+)
+
+// A CaptureStore retains captured responses by id and serves them over HTTP
+// for inspection.  The store is bounded in the number of captures and the
+// size of each body, evicting the oldest capture when full.  A CaptureStore
+// is safe for concurrent use.
+type CaptureStore struct {
+	max     int
+	maxBody int
+	mut     sync.Mutex
+	ids     []string
+	m       map[string]*Example
+}
+
+// NewCaptureStore returns a CaptureStore holding at most max captures of at
+// most maxBody body bytes each.  A negative maxBody is treated as zero.
+func NewCaptureStore(max, maxBody int) *CaptureStore {
+	if maxBody < 0 {
+		maxBody = 0
+	}
+	c := new(CaptureStore)
+	c.max = max
+	c.maxBody = maxBody
+	c.m = make(map[string]*Example)
+	return c
+}
+
+// Add stores the response observed by s under id, replacing any capture with
+// the same id.  Add should only be called after the handler has returned.
+func (c *CaptureStore) Add(id string, s WriteSpy) {
+	ex := newExample(id, s, c.maxBody)
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.max <= 0 {
+		return
+	}
+	if _, ok := c.m[id]; ok {
+		c.remove(id)
+	}
+	for len(c.ids) >= c.max {
+		c.remove(c.ids[0])
+	}
+	c.ids = append(c.ids, id)
+	c.m[id] = ex
+}
+
+// remove must be called with c.mut held.
+func (c *CaptureStore) remove(id string) {
+	delete(c.m, id)
+	for i := range c.ids {
+		if c.ids[i] == id {
+			c.ids = append(c.ids[:i], c.ids[i+1:]...)
+			return
+		}
+	}
+}
+
+// Get returns the capture stored under id.  The Route of the returned Example
+// is its id.
+func (c *CaptureStore) Get(id string) (Example, bool) {
+	c.mut.Lock()
+	ex, ok := c.m[id]
+	c.mut.Unlock()
+	if !ok {
+		return Example{}, false
+	}
+	return *ex, true
+}
+
+type captureSummary struct {
+	ID   string `json:"id"`
+	Code int    `json:"code"`
+	Size int    `json:"size"`
+}
+
+type captureDetail struct {
+	ID        string      `json:"id"`
+	Code      int         `json:"code"`
+	Header    http.Header `json:"header"`
+	Body      []byte      `json:"body"`
+	Truncated bool        `json:"truncated"`
+}
+
+// ServeHTTP serves the store as JSON.  Without an "id" query parameter it
+// lists the stored captures, oldest first.  Otherwise it serves the status,
+// headers and body of the capture with that id.  The body is base64 encoded
+// so binary responses survive the JSON encoding.
+func (c *CaptureStore) ServeHTTP(resp http.ResponseWriter, req *http.Request) {
+	if req.Method != "GET" && req.Method != "HEAD" {
+		resp.Header().Set("Allow", "GET, HEAD")
+		http.Error(resp, "only GET requests are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var v interface{}
+	if id := req.URL.Query().Get("id"); id != "" {
+		ex, ok := c.Get(id)
+		if !ok {
+			http.NotFound(resp, req)
+			return
+		}
+		v = captureDetail{id, ex.Code, ex.Header, ex.Body, ex.Truncated}
+	} else {
+		c.mut.Lock()
+		list := make([]captureSummary, len(c.ids))
+		for i, id := range c.ids {
+			list[i] = captureSummary{id, c.m[id].Code, len(c.m[id].Body)}
+		}
+		c.mut.Unlock()
+		v = list
+	}
+	resp.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(resp).Encode(v)
+}
@@ -0,0 +1,67 @@
+package httpspy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCaptureStore(t *testing.T) {
+	store := NewCaptureStore(2, 5)
+	for _, id := range []string{"a", "b", "c", "b"} {
+		s := NewWriteSpy(httptest.NewRecorder())
+		s.Header().Set("Content-Type", "text/plain")
+		io.WriteString(s, "body of "+id)
+		store.Add(id, s)
+	}
+
+	rec := httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if want := `[{"id":"c","code":200,"size":5},{"id":"b","code":200,"size":5}]`; strings.TrimSpace(rec.Body.String()) != want {
+		t.Errorf("list: %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest("GET", "/?id=c", nil))
+	if want := `{"id":"c","code":200,"header":{"Content-Type":["text/plain"]},"body":"Ym9keSA=","truncated":true}`; strings.TrimSpace(rec.Body.String()) != want {
+		t.Errorf("detail: %s", rec.Body)
+	}
+
+	rec = httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest("GET", "/?id=a", nil))
+	if rec.Code != 404 {
+		t.Errorf("evicted capture: %d", rec.Code)
+	}
+}
+
+func TestCaptureStoreBinary(t *testing.T) {
+	body := []byte{0xff, 0xfe, 0x00, 0x80, 'x'}
+	store := NewCaptureStore(1, 64)
+	s := NewWriteSpy(httptest.NewRecorder())
+	s.Header().Set("Content-Type", "application/octet-stream")
+	s.Write(body)
+	store.Add("bin", s)
+
+	rec := httptest.NewRecorder()
+	store.ServeHTTP(rec, httptest.NewRequest("GET", "/?id=bin", nil))
+	var detail struct{ Body []byte }
+	if err := json.Unmarshal(rec.Body.Bytes(), &detail); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(detail.Body, body) {
+		t.Errorf("body: %x", detail.Body)
+	}
+}
+
+func TestCaptureStoreNegativeMaxBody(t *testing.T) {
+	store := NewCaptureStore(1, -1)
+	s := NewWriteSpy(httptest.NewRecorder())
+	io.WriteString(s, "body")
+	store.Add("a", s)
+	if ex, ok := store.Get("a"); !ok || len(ex.Body) != 0 || !ex.Truncated {
+		t.Errorf("%+v %v", ex, ok)
+	}
+}
@@ -0,0 +1,212 @@
+package httpspy
+
+import (
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Timing breaks down the latency of a response.  All durations are zero if
+// the handler neither wrote a header nor a body.
+type Timing struct {
+	// TTFB is the time from the start of the request until the handler first
+	// called WriteHeader or Write.
+	TTFB time.Duration
+	// StreamDuration is the time from the first call to WriteHeader or Write
+	// until the last call to Write.  It is zero when no body was written.
+	StreamDuration time.Duration
+	// Total is the sum of TTFB and StreamDuration.
+	Total time.Duration
+}
+
+// A TimingSpy is a Spy that records when a response was written relative to
+// the start of its request.
+type TimingSpy interface {
+	Spy
+	// Timing returns the latency breakdown of the response so far.
+	Timing() Timing
+	// WithinSLO returns true if the total response time did not exceed
+	// target.  If nothing has been written the time elapsed since the
+	// request started is used instead, so a handler that never responds
+	// fails the SLO once target has passed.
+	WithinSLO(target time.Duration) bool
+	// SetUpstreamReady records when a proxied upstream response became
+	// available, typically when its headers arrived.
+	SetUpstreamReady(t time.Time)
+	// DownstreamStreamDuration returns the time from SetUpstreamReady until
+	// the last call to Write.  The total response time is returned if
+	// SetUpstreamReady was never called, and zero if nothing was written or
+	// the upstream became ready after the last write.
+	DownstreamStreamDuration() time.Duration
+	// BytesWritten returns the number of body bytes successfully written.
+	BytesWritten() int64
+	// ThroughputBytesPerSec returns BytesWritten divided by the time from
+	// the first call to Write until the last.  Zero is returned when fewer
+	// than two writes were made, as the interval is then undefined.
+	ThroughputBytesPerSec() float64
+}
+
+// NewTimingSpy returns a generic, threadsafe TimingSpy implementation.  The
+// start time should be obtained with time.Now so that durations are computed
+// with the monotonic clock.
+func NewTimingSpy(w http.ResponseWriter, start time.Time) TimingSpy {
+	s := new(timingSpy)
+	s.simpleSpy = new(simpleSpy)
+	s.simpleSpy.w = w
+	s.start = start
+	return s
+}
+
+type timingSpy struct {
+	*simpleSpy
+	mut   sync.Mutex
+	start time.Time
+	first time.Time
+	last  time.Time
+	ready time.Time
+
+	firstWrite time.Time
+	n          int64
+}
+
+func (s *timingSpy) WriteHeader(code int) {
+	s.simpleSpy.WriteHeader(code)
+	now := time.Now()
+	s.mut.Lock()
+	if s.first.IsZero() {
+		s.first = now
+	}
+	s.mut.Unlock()
+}
+
+func (s *timingSpy) Write(p []byte) (int, error) {
+	n, err := s.simpleSpy.Write(p)
+	now := time.Now()
+	s.mut.Lock()
+	if s.first.IsZero() {
+		s.first = now
+	}
+	if s.firstWrite.IsZero() {
+		s.firstWrite = now
+	}
+	s.last = now
+	s.n += int64(n)
+	s.mut.Unlock()
+	return n, err
+}
+
+func (s *timingSpy) Timing() Timing {
+	s.mut.Lock()
+	first, last := s.first, s.last
+	s.mut.Unlock()
+
+	var t Timing
+	if first.IsZero() {
+		return t
+	}
+	t.TTFB = first.Sub(s.start)
+	if !last.IsZero() {
+		t.StreamDuration = last.Sub(first)
+	}
+	t.Total = t.TTFB + t.StreamDuration
+	return t
+}
+
+func (s *timingSpy) WithinSLO(target time.Duration) bool {
+	s.mut.Lock()
+	first := s.first
+	s.mut.Unlock()
+	if first.IsZero() {
+		return time.Since(s.start) <= target
+	}
+	return s.Timing().Total <= target
+}
+
+func (s *timingSpy) SetUpstreamReady(t time.Time) {
+	s.mut.Lock()
+	s.ready = t
+	s.mut.Unlock()
+}
+
+func (s *timingSpy) DownstreamStreamDuration() time.Duration {
+	s.mut.Lock()
+	ready, last := s.ready, s.last
+	s.mut.Unlock()
+
+	switch {
+	case last.IsZero():
+		return 0
+	case ready.IsZero():
+		return s.Timing().Total
+	case ready.After(last):
+		return 0
+	}
+	return last.Sub(ready)
+}
+
+func (s *timingSpy) BytesWritten() int64 {
+	s.mut.Lock()
+	n := s.n
+	s.mut.Unlock()
+	return n
+}
+
+func (s *timingSpy) ThroughputBytesPerSec() float64 {
+	s.mut.Lock()
+	n, d := s.n, s.last.Sub(s.firstWrite)
+	s.mut.Unlock()
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) / d.Seconds()
+}
+
+// An SLO computes the ratio of responses served within a target latency.  An
+// SLO is safe for concurrent use.
+type SLO struct {
+	target time.Duration
+	mut    sync.Mutex
+	met    int64
+	total  int64
+}
+
+// NewSLO returns an SLO for responses with the given target latency.
+func NewSLO(target time.Duration) *SLO {
+	return &SLO{target: target}
+}
+
+// Target returns the target latency of o.
+func (o *SLO) Target() time.Duration {
+	return o.target
+}
+
+// Add records the response observed by s.  Add should only be called after
+// the handler has returned.
+func (o *SLO) Add(s TimingSpy) {
+	ok := s.WithinSLO(o.target)
+	o.mut.Lock()
+	o.total++
+	if ok {
+		o.met++
+	}
+	o.mut.Unlock()
+}
+
+// Counts returns the number of responses that met the target and the total
+// number of responses added.
+func (o *SLO) Counts() (met, total int64) {
+	o.mut.Lock()
+	met, total = o.met, o.total
+	o.mut.Unlock()
+	return met, total
+}
+
+// Ratio returns the fraction of responses that met the target.  Ratio returns
+// 1 if no responses have been added.
+func (o *SLO) Ratio() float64 {
+	met, total := o.Counts()
+	if total == 0 {
+		return 1
+	}
+	return float64(met) / float64(total)
+}
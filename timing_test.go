@@ -0,0 +1,99 @@
+package httpspy
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTimingSpy(t *testing.T) {
+	s := NewTimingSpy(httptest.NewRecorder(), time.Now())
+	if tm := s.Timing(); tm != (Timing{}) {
+		t.Fatalf("unexpected timing before write: %+v", tm)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	s.WriteHeader(204)
+	tm := s.Timing()
+	if tm.TTFB <= 0 || tm.StreamDuration != 0 || tm.Total != tm.TTFB {
+		t.Fatalf("unexpected timing without body: %+v", tm)
+	}
+
+	time.Sleep(2 * time.Millisecond)
+	s.Write([]byte("x"))
+	tm = s.Timing()
+	if tm.StreamDuration <= 0 || tm.Total != tm.TTFB+tm.StreamDuration {
+		t.Fatalf("unexpected timing: %+v", tm)
+	}
+}
+
+func TestSLO(t *testing.T) {
+	slo := NewSLO(time.Hour)
+	if r := slo.Ratio(); r != 1 {
+		t.Fatalf("ratio with no responses: %v", r)
+	}
+
+	fast := NewTimingSpy(httptest.NewRecorder(), time.Now())
+	fast.Write([]byte("fast"))
+	slo.Add(fast)
+
+	slow := NewTimingSpy(httptest.NewRecorder(), time.Now().Add(-2*time.Hour))
+	slow.Write([]byte("slow"))
+	if slow.WithinSLO(time.Hour) {
+		t.Errorf("slow response within SLO")
+	}
+	slo.Add(slow)
+
+	// A handler that never wrote is measured until now.
+	silent := NewTimingSpy(httptest.NewRecorder(), time.Now().Add(-2*time.Hour))
+	if silent.WithinSLO(time.Hour) {
+		t.Errorf("silent response within SLO")
+	}
+	if !NewTimingSpy(httptest.NewRecorder(), time.Now()).WithinSLO(time.Hour) {
+		t.Errorf("recent silent response outside SLO")
+	}
+	slo.Add(silent)
+
+	if met, total := slo.Counts(); met != 1 || total != 3 {
+		t.Errorf("met=%d total=%d", met, total)
+	}
+	if r := slo.Ratio(); r != 1.0/3 {
+		t.Errorf("ratio: %v", r)
+	}
+}
+
+func TestTimingSpyUpstreamReady(t *testing.T) {
+	start := time.Now()
+	s := NewTimingSpy(httptest.NewRecorder(), start.Add(-time.Hour))
+	if d := s.DownstreamStreamDuration(); d != 0 {
+		t.Errorf("duration before write: %v", d)
+	}
+	s.Write([]byte("x"))
+	if d := s.DownstreamStreamDuration(); d != s.Timing().Total {
+		t.Errorf("duration without upstream: %v", d)
+	}
+	s.SetUpstreamReady(start)
+	if d := s.DownstreamStreamDuration(); d <= 0 || d >= time.Hour {
+		t.Errorf("duration with upstream: %v", d)
+	}
+	s.SetUpstreamReady(time.Now().Add(time.Second))
+	if d := s.DownstreamStreamDuration(); d != 0 {
+		t.Errorf("duration with upstream ready after last write: %v", d)
+	}
+}
+
+func TestTimingSpyThroughput(t *testing.T) {
+	s := NewTimingSpy(httptest.NewRecorder(), time.Now())
+	s.Write(make([]byte, 1000))
+	if r := s.ThroughputBytesPerSec(); r != 0 {
+		t.Errorf("single write throughput: %v", r)
+	}
+	time.Sleep(10 * time.Millisecond)
+	s.Write(make([]byte, 1000))
+	if s.BytesWritten() != 2000 {
+		t.Errorf("bytes written: %d", s.BytesWritten())
+	}
+	if r := s.ThroughputBytesPerSec(); r <= 0 || r > 200000 {
+		t.Errorf("throughput: %v", r)
+	}
+}
@@ -0,0 +1,93 @@
+package httpspy
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// A TraceRecord is a captured request/response interaction.  TraceRecords are
+// written by a TraceWriter as newline-delimited JSON objects of the form
+//
+//	{
+//		"time": "2006-01-02T15:04:05Z",
+//		"request": {"method": "GET", "url": "http://host/path", "header": {...}, "body": "base64"},
+//		"response": {"code": 200, "header": {...}, "body": "base64"}
+//	}
+//
+// Header objects map canonical header names to arrays of values.  Bodies are
+// base64 encoded and omitted when empty.  Fields may be added to the schema
+// but existing fields will not change meaning.
+type TraceRecord struct {
+	Time     time.Time     `json:"time"`
+	Request  TraceRequest  `json:"request"`
+	Response TraceResponse `json:"response"`
+}
+
+// A TraceRequest is the request half of a TraceRecord.
+type TraceRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// A TraceResponse is the response half of a TraceRecord.
+type TraceResponse struct {
+	Code   int         `json:"code"`
+	Header http.Header `json:"header,omitempty"`
+	Body   []byte      `json:"body,omitempty"`
+}
+
+// NewTraceRecord returns a record of req, which had the body reqBody, and the
+// response observed by s.  The record URL is absolute, using req.Host and the
+// scheme the request was received with when req.URL is relative.
+// NewTraceRecord should only be called after the handler has returned.
+func NewTraceRecord(req *http.Request, reqBody []byte, s WriteSpy) TraceRecord {
+	u := *req.URL
+	if u.Host == "" {
+		u.Host = req.Host
+	}
+	if u.Scheme == "" {
+		u.Scheme = "http"
+		if req.TLS != nil {
+			u.Scheme = "https"
+		}
+	}
+	return TraceRecord{
+		Time: time.Now().UTC(),
+		Request: TraceRequest{
+			Method: req.Method,
+			URL:    u.String(),
+			Header: cloneHeader(req.Header),
+			Body:   append([]byte(nil), reqBody...),
+		},
+		Response: TraceResponse{
+			Code:   s.Code(),
+			Header: cloneHeader(s.Header()),
+			Body:   append([]byte(nil), s.Body()...),
+		},
+	}
+}
+
+// A TraceWriter writes TraceRecords as newline-delimited JSON.  A TraceWriter
+// is safe for concurrent use.
+type TraceWriter struct {
+	mut sync.Mutex
+	enc *json.Encoder
+}
+
+// NewTraceWriter returns a TraceWriter writing to w.
+func NewTraceWriter(w io.Writer) *TraceWriter {
+	return &TraceWriter{enc: json.NewEncoder(w)}
+}
+
+// Write writes rec as a single line.
+func (t *TraceWriter) Write(rec TraceRecord) error {
+	t.mut.Lock()
+	err := t.enc.Encode(rec)
+	t.mut.Unlock()
+	return err
+}
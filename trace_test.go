@@ -0,0 +1,49 @@
+package httpspy
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTraceWriter(t *testing.T) {
+	req := httptest.NewRequest("POST", "/pets?name=bowser", strings.NewReader("woof"))
+	req.Header.Set("Content-Type", "text/plain")
+	s := NewWriteSpy(httptest.NewRecorder())
+	s.Header().Set("Content-Type", "application/json")
+	s.WriteHeader(201)
+	io.WriteString(s, `{"id":1}`)
+	rec := NewTraceRecord(req, []byte("woof"), s)
+
+	var buf bytes.Buffer
+	tw := NewTraceWriter(&buf)
+	if err := tw.Write(rec); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Write(rec); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("%d lines", len(lines))
+	}
+	var got TraceRecord
+	if err := json.Unmarshal([]byte(lines[1]), &got); err != nil {
+		t.Fatal(err)
+	}
+	if !got.Time.Equal(rec.Time) {
+		t.Errorf("time %v != %v", got.Time, rec.Time)
+	}
+	got.Time = rec.Time
+	if !reflect.DeepEqual(got, rec) {
+		t.Errorf("record %+v != %+v", got, rec)
+	}
+	if got.Request.URL != "http://example.com/pets?name=bowser" {
+		t.Errorf("url %q", got.Request.URL)
+	}
+}
@@ -0,0 +1,114 @@
+package httpspy
+
+import (
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// A TransformSpy is a Spy that streams the response body through a
+// transformation, such as a reformatter or encoder, on its way to the
+// underlying http.ResponseWriter.  Nothing is buffered by the spy itself so
+// writes block until the transformation accepts them.
+type TransformSpy interface {
+	Spy
+	// BytesWritten returns the number of body bytes written by the handler,
+	// before transformation.
+	BytesWritten() int64
+	// TransformedBytes returns the number of body bytes the transformation
+	// wrote to the underlying writer.
+	TransformedBytes() int64
+	// Close closes the transformation, flushing any output it buffered.
+	// Close must be called after the handler returns or the response may be
+	// truncated.  Calls to Write after Close fail with io.ErrClosedPipe.
+	Close() error
+}
+
+// NewTransformSpy returns a generic, threadsafe TransformSpy.  The transform
+// function is called once, on the first call to Write, with a writer for the
+// underlying response body and returns the writer the handler's bytes are
+// passed through.  Because the transformed body length is not known in
+// advance, any Content-Length header is removed before the response header is
+// written.
+func NewTransformSpy(w http.ResponseWriter, transform func(io.Writer) io.WriteCloser) TransformSpy {
+	s := new(transformSpy)
+	s.simpleSpy = new(simpleSpy)
+	s.simpleSpy.w = w
+	s.transform = transform
+	return s
+}
+
+type transformSpy struct {
+	*simpleSpy
+	transform func(io.Writer) io.WriteCloser
+	mut       sync.Mutex
+	tw        io.WriteCloser
+	closed    bool
+	in        int64
+	out       int64 // updated atomically by transformOutput
+}
+
+func (s *transformSpy) WriteHeader(code int) {
+	s.simpleSpy.Header().Del("Content-Length")
+	s.simpleSpy.WriteHeader(code)
+}
+
+func (s *transformSpy) Write(p []byte) (int, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if s.tw == nil {
+		s.simpleSpy.Header().Del("Content-Length")
+		s.tw = s.transform(transformOutput{s})
+	}
+	n, err := s.tw.Write(p)
+	s.in += int64(n)
+	return n, err
+}
+
+func (s *transformSpy) Close() error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	if s.tw == nil {
+		return nil
+	}
+	return s.tw.Close()
+}
+
+func (s *transformSpy) Code() int {
+	code := s.simpleSpy.Code()
+	if code == 0 && s.BytesWritten() > 0 {
+		return http.StatusOK
+	}
+	return code
+}
+
+func (s *transformSpy) BytesWritten() int64 {
+	s.mut.Lock()
+	n := s.in
+	s.mut.Unlock()
+	return n
+}
+
+func (s *transformSpy) TransformedBytes() int64 {
+	return atomic.LoadInt64(&s.out)
+}
+
+// transformOutput receives the output of a transformSpy's transformation.  It
+// may be called from goroutines started by the transformation.
+type transformOutput struct {
+	s *transformSpy
+}
+
+func (w transformOutput) Write(p []byte) (int, error) {
+	n, err := w.s.simpleSpy.Write(p)
+	atomic.AddInt64(&w.s.out, int64(n))
+	return n, err
+}
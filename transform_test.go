@@ -0,0 +1,66 @@
+package httpspy
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"testing"
+)
+
+// quoteLines prefixes each complete line with "> ", holding back partial
+// lines until they are completed or the writer is closed.
+type quoteLines struct {
+	w   io.Writer
+	buf bytes.Buffer
+}
+
+func (u *quoteLines) Write(p []byte) (int, error) {
+	u.buf.Write(p)
+	for {
+		i := bytes.IndexByte(u.buf.Bytes(), '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := u.buf.Next(i + 1)
+		if _, err := io.WriteString(u.w, "> "+string(line)); err != nil {
+			return len(p), err
+		}
+	}
+}
+
+func (u *quoteLines) Close() error {
+	if u.buf.Len() == 0 {
+		return nil
+	}
+	_, err := io.WriteString(u.w, "> "+u.buf.String()+"\n")
+	return err
+}
+
+func TestTransformSpy(t *testing.T) {
+	rec := httptest.NewRecorder()
+	s := NewTransformSpy(rec, func(w io.Writer) io.WriteCloser {
+		return &quoteLines{w: w}
+	})
+	s.Header().Set("Content-Length", "12")
+	io.WriteString(s, "one\ntw")
+	if s.Code() != 200 || s.TransformedBytes() != 6 {
+		t.Errorf("code=%d transformed=%d", s.Code(), s.TransformedBytes())
+	}
+	io.WriteString(s, "o\nthree")
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.WriteString(s, "four"); err != io.ErrClosedPipe {
+		t.Errorf("write after close: %v", err)
+	}
+
+	if want := "> one\n> two\n> three\n"; rec.Body.String() != want {
+		t.Errorf("body %q", rec.Body)
+	}
+	if s.BytesWritten() != 13 || s.TransformedBytes() != 20 {
+		t.Errorf("in=%d out=%d", s.BytesWritten(), s.TransformedBytes())
+	}
+	if rec.Header().Get("Content-Length") != "" {
+		t.Errorf("Content-Length not removed")
+	}
+}
@@ -0,0 +1,58 @@
+/*
+Package vegeta encodes httpspy traces as targets for the vegeta load testing
+tool, in its JSON target format.
+
+	vegeta attack -format=json -targets=targets.json
+
+Like httpspy, the vegeta API is experimental and may change without notice.
+*/
+package vegeta
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/bmatsuo/httpspy"
+)
+
+// Hop-by-hop and transport headers that vegeta sets itself.
+var skipHeaders = []string{
+	"Connection", "Content-Length", "Keep-Alive", "Proxy-Connection",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+type target struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Body   []byte      `json:"body,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+}
+
+// An Encoder writes the request of each trace record as a vegeta JSON target.
+// The response half of the record is ignored.
+type Encoder struct {
+	enc *json.Encoder
+}
+
+// NewEncoder returns an Encoder writing targets to w, one per line.
+func NewEncoder(w io.Writer) *Encoder {
+	return &Encoder{enc: json.NewEncoder(w)}
+}
+
+// Encode implements httpspy.ScenarioEncoder.
+func (e *Encoder) Encode(rec httpspy.TraceRecord) error {
+	header := make(http.Header, len(rec.Request.Header))
+	for k, v := range rec.Request.Header {
+		header[k] = v
+	}
+	for _, k := range skipHeaders {
+		delete(header, k)
+	}
+	return e.enc.Encode(target{
+		Method: rec.Request.Method,
+		URL:    rec.Request.URL,
+		Body:   rec.Request.Body,
+		Header: header,
+	})
+}
@@ -0,0 +1,33 @@
+package vegeta
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bmatsuo/httpspy"
+)
+
+func TestEncoder(t *testing.T) {
+	req := httptest.NewRequest("POST", "/pets", strings.NewReader("woof"))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Connection", "keep-alive")
+	s := httpspy.NewWriteSpy(httptest.NewRecorder())
+	s.WriteHeader(201)
+
+	var trace bytes.Buffer
+	tw := httpspy.NewTraceWriter(&trace)
+	tw.Write(httpspy.NewTraceRecord(req, []byte("woof"), s))
+	tw.Write(httpspy.NewTraceRecord(httptest.NewRequest("GET", "/pets", nil), nil, s))
+
+	var out bytes.Buffer
+	if err := httpspy.ExportScenario(&trace, NewEncoder(&out)); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"method":"POST","url":"http://example.com/pets","body":"d29vZg==","header":{"Content-Type":["text/plain"]}}` + "\n" +
+		`{"method":"GET","url":"http://example.com/pets"}` + "\n"
+	if out.String() != want {
+		t.Errorf("targets:\n%s", out.String())
+	}
+}